@@ -32,59 +32,100 @@ func NewAStar() *AStar {
 }
 
 func (a *AStar) CreateFirstNode(col int, row int) PathNode {
-	return NewAStarNode(nil, nil, 0, col, row)
+	return a.newNode(nil, 0, col, row)
+}
+
+// reuse a node of the former searches if there is one
+func (a *AStar) newNode(parent PathNode, minGValue uint32, col int, row int) *AStarNode {
+	pooled, ok := a.GetPooledNode()
+	if ok {
+		node := pooled.(*AStarNode)
+		node.Init(parent, nil, minGValue, col, row)
+		return node
+	}
+
+	node := NewAStarNode(parent, nil, minGValue, col, row)
+	a.AddNodeToPool(node)
+	return node
 }
 
 func (a *AStar) UnfoldGrid(m NavigationMap, dstGrid *Grid, node PathNode) {
 	grid := node.GetGrid()
-	if a.handleGrid(m, grid.Col-1, grid.Row, node, dstGrid) {
+	neighbourMap, ok := m.(NeighbourMap)
+	if ok {
+		a.unfoldNeighbours(m, neighbourMap, dstGrid, node)
 		return
 	}
 
-	if a.handleGrid(m, grid.Col+1, grid.Row, node, dstGrid) {
-		return
-	}
+	a.handleGrid(m, grid.Col-1, grid.Row, node, dstGrid)
 
-	if a.handleGrid(m, grid.Col, grid.Row-1, node, dstGrid) {
-		return
-	}
+	a.handleGrid(m, grid.Col+1, grid.Row, node, dstGrid)
 
-	if a.handleGrid(m, grid.Col, grid.Row+1, node, dstGrid) {
-		return
+	a.handleGrid(m, grid.Col, grid.Row-1, node, dstGrid)
+
+	a.handleGrid(m, grid.Col, grid.Row+1, node, dstGrid)
+
+	for _, link := range getLinks(m, grid.Col, grid.Row) {
+		a.handleLink(m, link, node, dstGrid)
 	}
 
 	a.AddNodeToCloseList(node)
 }
 
-func (a *AStar) handleGrid(m NavigationMap, col int, row int, parent PathNode, dstGrid *Grid) bool {
-	// find out dest grid
-	if dstGrid.IsSameGrid2(col, row) {
-		a.lastNode = NewAStarNode(parent, nil, 0, col, row)
-		return true
+// the map decides the neighbours
+func (a *AStar) unfoldNeighbours(m NavigationMap, neighbourMap NeighbourMap, dstGrid *Grid, node PathNode) {
+	grid := node.GetGrid()
+	for _, neighbour := range neighbourMap.GetNeighbours(grid.Col, grid.Row) {
+		a.handleGrid(m, neighbour.Col, neighbour.Row, node, dstGrid)
+	}
+
+	for _, link := range getLinks(m, grid.Col, grid.Row) {
+		a.handleLink(m, link, node, dstGrid)
 	}
 
+	a.AddNodeToCloseList(node)
+}
+
+func (a *AStar) handleGrid(m NavigationMap, col int, row int, parent PathNode, dstGrid *Grid) {
 	// parent grid, skip
 	grid := parent.GetGrid()
 	if grid.IsSameGrid2(col, row) {
-		return false
+		return
 	}
 
 	// can't cross, skip
-	if !m.CanCross(col, row) {
-		return false
+	addGValue, ok := getEdgeGValue(m, grid.Col, grid.Row, col, row)
+	if !ok {
+		return
 	}
 
-	addGValue := m.GetGValue(col, row)
 	minGValue := parent.GetMinGValue() + addGValue
 
 	// already in open list or close list, update min G value
 	if a.UpdateExistList(m, col, row, parent, nil, minGValue) {
-		return false
+		return
 	}
 
 	// new grid, add to open list
-	node := NewAStarNode(parent, nil, minGValue, col, row)
+	node := a.newNode(parent, minGValue, col, row)
 	a.AddNodeToOpenList(node)
+}
 
-	return false
+func (a *AStar) handleLink(m NavigationMap, link *Link, parent PathNode, dstGrid *Grid) {
+	// can't cross, skip
+	if !m.CanCross(link.To.Col, link.To.Row) {
+		return
+	}
+
+	minGValue := parent.GetMinGValue() + link.GValue
+
+	// already in open list or close list, update min G value
+	if a.UpdateExistListByLink(m, parent, link, minGValue) {
+		return
+	}
+
+	// new grid, add to open list
+	node := a.newNode(parent, minGValue, link.To.Col, link.To.Row)
+	setNodeLink(node, link)
+	a.AddNodeToOpenList(node)
 }
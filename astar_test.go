@@ -0,0 +1,70 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// '#' can't be crossed, '1' - '9' is the g value, the others are g value 1
+func newTestMap(t *testing.T, lines ...string) *GridMap {
+	m, err := LoadAsciiMap(strings.NewReader(strings.Join(lines, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+// the grids can't be crossed at the density, the others have g value 1 - maxGValue
+func newRandomTestMap(seed int64, cols int, rows int, density float64, maxGValue int) *GridMap {
+	rnd := rand.New(rand.NewSource(seed))
+	m := NewGridMap(uint32(cols), uint32(rows), 1)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if rnd.Float64() < density {
+				m.SetCanCross(col, row, false)
+				continue
+			}
+
+			m.SetGValue(col, row, uint32(1+rnd.Intn(maxGValue)))
+		}
+	}
+
+	return m
+}
+
+func getPathGValue(t *testing.T, finder PathFinder, m NavigationMap, startGrid *Grid, dstGrid *Grid) (uint32, bool) {
+	fullPath, ok := finder.FindPath(m, startGrid, dstGrid)
+	if !ok {
+		return 0, false
+	}
+
+	lastNode := fullPath[len(fullPath)-1]
+	if !lastNode.GetGrid().IsSameGrid(dstGrid) {
+		t.Fatalf("path ends at %v, want %v", lastNode.GetGrid(), dstGrid)
+	}
+
+	return lastNode.GetMinGValue(), true
+}
+
+func TestAStarEdgeCost(t *testing.T) {
+	m := NewEdgeLayer(newTestMap(t, "...", "...", "..."))
+	m.SetEdge(NewGrid(1, 1), NewGrid(2, 1), 50, true)
+
+	finders := map[string]PathFinder{
+		"astar": NewAStar(),
+		"jps":   NewJps(0, false),
+	}
+
+	for name, finder := range finders {
+		gValue, ok := getPathGValue(t, finder, m, NewGrid(0, 1), NewGrid(2, 1))
+		if !ok || gValue != 4 {
+			t.Errorf("%s: got g value %d (%v), want 4", name, gValue, ok)
+		}
+	}
+}
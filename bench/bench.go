@@ -0,0 +1,205 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/yxlib/nav"
+)
+
+// keep it unchanged, so the results of different releases can be compared
+const DefaultSeed = 20220601
+
+// the count of the queries run in turn on each map
+const QueryCount = 64
+
+// the width and the height of the maps
+var Sizes = []int{64, 256, 1024, 2048}
+
+//========================
+//        Family
+//========================
+type Family struct {
+	Name string
+	Gen  func(size int, seed int64) *nav.GridMap
+}
+
+var Families = []*Family{
+	{Name: "open", Gen: func(size int, seed int64) *nav.GridMap { return GenOpenField(size, size) }},
+	{Name: "random10", Gen: func(size int, seed int64) *nav.GridMap { return GenRandomObstacles(size, size, 0.1, seed) }},
+	{Name: "random20", Gen: func(size int, seed int64) *nav.GridMap { return GenRandomObstacles(size, size, 0.2, seed) }},
+	{Name: "random30", Gen: func(size int, seed int64) *nav.GridMap { return GenRandomObstacles(size, size, 0.3, seed) }},
+	{Name: "maze", Gen: func(size int, seed int64) *nav.GridMap { return GenMaze(size, size, seed) }},
+	{Name: "rooms", Gen: func(size int, seed int64) *nav.GridMap { return GenRoomsAndCorridors(size, size, seed) }},
+	{Name: "weighted", Gen: func(size int, seed int64) *nav.GridMap { return GenWeightedTerrain(size, size, seed) }},
+}
+
+type Finder struct {
+	Name string
+	New  func() nav.PathFinder
+}
+
+var Finders = []*Finder{
+	{Name: "astar", New: func() nav.PathFinder { return nav.NewAStar() }},
+	{Name: "jps", New: func() nav.PathFinder { return nav.NewJps(0, false) }},
+	{Name: "jps8", New: func() nav.PathFinder { return nav.NewJps(0, true) }},
+}
+
+//========================
+//         Suite
+//========================
+// a benchmark named as finder/family/size
+type Case struct {
+	Name   string
+	Bench  func(b *testing.B)
+	mapKey string
+}
+
+type benchMap struct {
+	m       *nav.GridMap
+	queries []*query
+}
+
+type query struct {
+	startGrid nav.Grid
+	dstGrid   nav.Grid
+}
+
+// the maps are generated at the first use, and shared by the finders
+type Suite struct {
+	seed  int64
+	maps  map[string]*benchMap
+	cases []*Case
+}
+
+func NewSuite(seed int64) *Suite {
+	s := &Suite{
+		seed:  seed,
+		maps:  make(map[string]*benchMap),
+		cases: make([]*Case, 0),
+	}
+
+	for _, family := range Families {
+		for _, size := range Sizes {
+			for _, finder := range Finders {
+				s.addCase(family, size, finder)
+			}
+		}
+	}
+
+	return s
+}
+
+func (s *Suite) GetSeed() int64 {
+	return s.seed
+}
+
+func (s *Suite) GetCases() []*Case {
+	return s.cases
+}
+
+// run the cases matched, a map is dropped after all its cases run to keep the memory low
+func (s *Suite) Run(match func(name string) bool, report func(name string, result testing.BenchmarkResult)) {
+	for i, c := range s.cases {
+		if match == nil || match(c.Name) {
+			report(c.Name, testing.Benchmark(c.Bench))
+		}
+
+		s.dropMap(i)
+	}
+}
+
+// run the cases of the finder as the sub benchmarks of b, named as family/size
+func (s *Suite) RunFinder(b *testing.B, finderName string) {
+	prefix := finderName + "/"
+	for i, c := range s.cases {
+		if strings.HasPrefix(c.Name, prefix) {
+			b.Run(strings.TrimPrefix(c.Name, prefix), c.Bench)
+		}
+
+		s.dropMap(i)
+	}
+}
+
+// drop the map after the last case of it
+func (s *Suite) dropMap(i int) {
+	c := s.cases[i]
+	if i+1 == len(s.cases) || s.cases[i+1].mapKey != c.mapKey {
+		delete(s.maps, c.mapKey)
+	}
+}
+
+func (s *Suite) addCase(family *Family, size int, finder *Finder) {
+	mapKey := fmt.Sprintf("%s/%d", family.Name, size)
+	c := &Case{
+		Name:   finder.Name + "/" + mapKey,
+		mapKey: mapKey,
+	}
+
+	c.Bench = func(b *testing.B) {
+		bm := s.getMap(mapKey, family, size)
+		if len(bm.queries) == 0 {
+			b.Skip("no reachable query")
+		}
+
+		// warm up, so the nodes are reused as the steady state
+		f := finder.New()
+		f.FindPath(bm.m, &bm.queries[0].startGrid, &bm.queries[0].dstGrid)
+		b.ReportAllocs()
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			q := bm.queries[i%len(bm.queries)]
+			f.FindPath(bm.m, &q.startGrid, &q.dstGrid)
+		}
+	}
+
+	s.cases = append(s.cases, c)
+}
+
+func (s *Suite) getMap(mapKey string, family *Family, size int) *benchMap {
+	bm, ok := s.maps[mapKey]
+	if ok {
+		return bm
+	}
+
+	m := family.Gen(size, s.seed)
+	bm = &benchMap{
+		m:       m,
+		queries: genQueries(m, QueryCount, s.seed),
+	}
+
+	s.maps[mapKey] = bm
+	return bm
+}
+
+// the start and the dest of a query are connected orthogonally, so every finder can find the path
+func genQueries(m *nav.GridMap, count int, seed int64) []*query {
+	r := rand.New(rand.NewSource(seed))
+	componentIndex := nav.NewComponentIndex(m, nav.Connectivity4)
+	cols, rows := m.GetColRow()
+	queries := make([]*query, 0, count)
+	for tries := 0; tries < count*100 && len(queries) < count; tries++ {
+		q := &query{
+			startGrid: nav.Grid{Col: r.Intn(int(cols)), Row: r.Intn(int(rows))},
+			dstGrid:   nav.Grid{Col: r.Intn(int(cols)), Row: r.Intn(int(rows))},
+		}
+
+		if !m.CanCross(q.startGrid.Col, q.startGrid.Row) || !m.CanCross(q.dstGrid.Col, q.dstGrid.Row) {
+			continue
+		}
+
+		if q.startGrid.IsSameGrid(&q.dstGrid) || !componentIndex.IsReachable(&q.startGrid, &q.dstGrid) {
+			continue
+		}
+
+		queries = append(queries, q)
+	}
+
+	return queries
+}
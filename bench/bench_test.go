@@ -0,0 +1,31 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bench
+
+import "testing"
+
+// go test -bench . ./bench, or -bench AStar/maze to run a family only
+
+func BenchmarkAStar(b *testing.B) {
+	NewSuite(DefaultSeed).RunFinder(b, "astar")
+}
+
+func BenchmarkJps(b *testing.B) {
+	NewSuite(DefaultSeed).RunFinder(b, "jps")
+}
+
+func BenchmarkJps8(b *testing.B) {
+	NewSuite(DefaultSeed).RunFinder(b, "jps8")
+}
+
+// every family has the queries for the benchmarks
+func TestSuiteQueries(t *testing.T) {
+	for _, family := range Families {
+		m := family.Gen(Sizes[0], DefaultSeed)
+		if queries := genQueries(m, QueryCount, DefaultSeed); len(queries) == 0 {
+			t.Errorf("%s: no query", family.Name)
+		}
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bench generates the maps of several families and benchmarks the
+// finders on them, the same seed always generates the same maps and queries.
+package bench
+
+import (
+	"math/rand"
+
+	"github.com/yxlib/nav"
+)
+
+//========================
+//       Generators
+//========================
+// all the grids can be crossed
+func GenOpenField(cols int, rows int) *nav.GridMap {
+	return nav.NewGridMap(uint32(cols), uint32(rows), 1)
+}
+
+// the density of the grids can't be crossed, between 0 and 1
+func GenRandomObstacles(cols int, rows int, density float64, seed int64) *nav.GridMap {
+	r := rand.New(rand.NewSource(seed))
+	m := nav.NewGridMap(uint32(cols), uint32(rows), 1)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			if r.Float64() < density {
+				m.SetCanCross(col, row, false)
+			}
+		}
+	}
+
+	return m
+}
+
+// a perfect maze with corridors of one grid, carved by a depth first search
+func GenMaze(cols int, rows int, seed int64) *nav.GridMap {
+	r := rand.New(rand.NewSource(seed))
+	m := genBlockedMap(cols, rows)
+
+	// the cells are at the odd grids, the walls between them
+	cellCols := (cols - 1) / 2
+	cellRows := (rows - 1) / 2
+	if cellCols <= 0 || cellRows <= 0 {
+		return m
+	}
+
+	visited := make([]bool, cellCols*cellRows)
+	stack := []int{0}
+	visited[0] = true
+	m.SetCanCross(1, 1, true)
+	dirs := [][2]int{{0, -1}, {1, 0}, {0, 1}, {-1, 0}}
+	next := make([]int, 0, 4)
+	for len(stack) > 0 {
+		cell := stack[len(stack)-1]
+		cx := cell % cellCols
+		cy := cell / cellCols
+
+		next = next[:0]
+		for _, dir := range dirs {
+			nx := cx + dir[0]
+			ny := cy + dir[1]
+			if nx < 0 || ny < 0 || nx >= cellCols || ny >= cellRows || visited[ny*cellCols+nx] {
+				continue
+			}
+
+			next = append(next, ny*cellCols+nx)
+		}
+
+		if len(next) == 0 {
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		nextCell := next[r.Intn(len(next))]
+		nx := nextCell % cellCols
+		ny := nextCell / cellCols
+		visited[nextCell] = true
+		m.SetCanCross(cx+nx+1, cy+ny+1, true)
+		m.SetCanCross(nx*2+1, ny*2+1, true)
+		stack = append(stack, nextCell)
+	}
+
+	return m
+}
+
+// the rectangle rooms linked by the corridors in L shape, the rooms cover about
+// a quarter of the map
+func GenRoomsAndCorridors(cols int, rows int, seed int64) *nav.GridMap {
+	const maxRoomSize = 16
+
+	r := rand.New(rand.NewSource(seed))
+	m := genBlockedMap(cols, rows)
+	roomCount := maxInt(cols*rows/(maxRoomSize*maxRoomSize*4), 2)
+	centers := make([]nav.Grid, 0, roomCount)
+	for i := 0; i < roomCount; i++ {
+		width := 3 + r.Intn(maxRoomSize-2)
+		height := 3 + r.Intn(maxRoomSize-2)
+		if width >= cols-2 || height >= rows-2 {
+			continue
+		}
+
+		left := 1 + r.Intn(cols-width-1)
+		top := 1 + r.Intn(rows-height-1)
+		fillRect(m, left, top, left+width-1, top+height-1)
+		centers = append(centers, nav.Grid{Col: left + width/2, Row: top + height/2})
+	}
+
+	for i := 1; i < len(centers); i++ {
+		from := centers[i-1]
+		to := centers[i]
+		if r.Intn(2) == 0 {
+			fillRect(m, minInt(from.Col, to.Col), from.Row, maxInt(from.Col, to.Col), from.Row)
+			fillRect(m, to.Col, minInt(from.Row, to.Row), to.Col, maxInt(from.Row, to.Row))
+		} else {
+			fillRect(m, from.Col, minInt(from.Row, to.Row), from.Col, maxInt(from.Row, to.Row))
+			fillRect(m, minInt(from.Col, to.Col), to.Row, maxInt(from.Col, to.Col), to.Row)
+		}
+	}
+
+	return m
+}
+
+// all the grids can be crossed, the g values between 1 and 9 come from a smooth noise
+func GenWeightedTerrain(cols int, rows int, seed int64) *nav.GridMap {
+	const step = 16
+
+	r := rand.New(rand.NewSource(seed))
+	m := nav.NewGridMap(uint32(cols), uint32(rows), 1)
+	noiseCols := cols/step + 2
+	noiseRows := rows/step + 2
+	noise := make([]float64, noiseCols*noiseRows)
+	for i := range noise {
+		noise[i] = r.Float64()
+	}
+
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			// bilinear interpolation of the noise around
+			nx := col / step
+			ny := row / step
+			fx := float64(col%step) / step
+			fy := float64(row%step) / step
+			top := noise[ny*noiseCols+nx]*(1-fx) + noise[ny*noiseCols+nx+1]*fx
+			bottom := noise[(ny+1)*noiseCols+nx]*(1-fx) + noise[(ny+1)*noiseCols+nx+1]*fx
+			m.SetGValue(col, row, getTerrainGValue(top*(1-fy)+bottom*fy))
+		}
+	}
+
+	return m
+}
+
+func getTerrainGValue(v float64) uint32 {
+	switch {
+	case v < 0.5:
+		return 1
+	case v < 0.7:
+		return 3
+	case v < 0.85:
+		return 5
+	default:
+		return 9
+	}
+}
+
+func genBlockedMap(cols int, rows int) *nav.GridMap {
+	m := nav.NewGridMap(uint32(cols), uint32(rows), 1)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			m.SetCanCross(col, row, false)
+		}
+	}
+
+	return m
+}
+
+func fillRect(m *nav.GridMap, left int, top int, right int, bottom int) {
+	for row := top; row <= bottom; row++ {
+		for col := left; col <= right; col++ {
+			m.SetCanCross(col, row, true)
+		}
+	}
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,178 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "sync"
+
+//========================
+//  ChunkedNavigationMap
+//========================
+// load the chunk at the chunk coordinate, the chunk map has the size of chunkSize x chunkSize
+// and uses the local coordinate, return nil if there is no chunk, so all its grids can't be crossed
+type ChunkProvider func(chunkCol int, chunkRow int) NavigationMap
+
+type loadedChunk struct {
+	m       NavigationMap
+	lastUse uint64
+}
+
+// an unbounded map made of fixed-size chunks, the chunks are loaded on demand,
+// the grid coordinate can be negative
+//
+// the loaded chunks are locked, so the map can be searched by the workers of a
+// PathService, the provider is called in the lock, and the chunk maps should not
+// be changed while searching
+type ChunkedNavigationMap struct {
+	chunkSize     int
+	provider      ChunkProvider
+	minGValue     uint32
+	maxChunkCount int
+	mutex         sync.Mutex
+	chunks        map[Grid]*loadedChunk
+	useCount      uint64
+}
+
+// minGValue should not be greater than any g value of the world, since the chunks
+// not loaded are unknown. maxChunkCount is the max count of the loaded chunks, the
+// chunk not used for the longest time is unloaded, 0 means no limit
+func NewChunkedNavigationMap(chunkSize uint32, provider ChunkProvider, minGValue uint32, maxChunkCount int) *ChunkedNavigationMap {
+	if chunkSize == 0 {
+		chunkSize = 1
+	}
+
+	return &ChunkedNavigationMap{
+		chunkSize:     int(chunkSize),
+		provider:      provider,
+		minGValue:     minGValue,
+		maxChunkCount: maxChunkCount,
+		chunks:        make(map[Grid]*loadedChunk),
+	}
+}
+
+func (m *ChunkedNavigationMap) GetChunkSize() uint32 {
+	return uint32(m.chunkSize)
+}
+
+// the chunk coordinate of a grid
+func (m *ChunkedNavigationMap) GetChunkColRow(col int, row int) (chunkCol int, chunkRow int) {
+	return floorDiv(col, m.chunkSize), floorDiv(row, m.chunkSize)
+}
+
+// load the chunk if it is not loaded
+func (m *ChunkedNavigationMap) LoadChunk(chunkCol int, chunkRow int) NavigationMap {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := Grid{Col: chunkCol, Row: chunkRow}
+	chunk, ok := m.chunks[key]
+	if !ok {
+		m.unloadUnused()
+		chunk = &loadedChunk{
+			m: m.provider(chunkCol, chunkRow),
+		}
+
+		m.chunks[key] = chunk
+	}
+
+	m.useCount++
+	chunk.lastUse = m.useCount
+	return chunk.m
+}
+
+// unload the chunk, it will be loaded again when used, call it after the chunk changed
+func (m *ChunkedNavigationMap) UnloadChunk(chunkCol int, chunkRow int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	delete(m.chunks, Grid{Col: chunkCol, Row: chunkRow})
+}
+
+func (m *ChunkedNavigationMap) UnloadAll() {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.chunks = make(map[Grid]*loadedChunk)
+}
+
+func (m *ChunkedNavigationMap) IsChunkLoaded(chunkCol int, chunkRow int) bool {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	_, ok := m.chunks[Grid{Col: chunkCol, Row: chunkRow}]
+	return ok
+}
+
+func (m *ChunkedNavigationMap) GetLoadedChunkCount() int {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return len(m.chunks)
+}
+
+func (m *ChunkedNavigationMap) IsUnbounded() bool {
+	return true
+}
+
+// no global bounds
+func (m *ChunkedNavigationMap) GetColRow() (col uint32, row uint32) {
+	return 0, 0
+}
+
+func (m *ChunkedNavigationMap) CanCross(col int, row int) bool {
+	chunk, localCol, localRow := m.getChunk(col, row)
+	if chunk == nil {
+		return false
+	}
+
+	return chunk.CanCross(localCol, localRow)
+}
+
+func (m *ChunkedNavigationMap) GetGValue(col int, row int) uint32 {
+	chunk, localCol, localRow := m.getChunk(col, row)
+	if chunk == nil {
+		return 0
+	}
+
+	return chunk.GetGValue(localCol, localRow)
+}
+
+func (m *ChunkedNavigationMap) GetMinGValue() uint32 {
+	return m.minGValue
+}
+
+func (m *ChunkedNavigationMap) getChunk(col int, row int) (NavigationMap, int, int) {
+	chunkCol, chunkRow := m.GetChunkColRow(col, row)
+	chunk := m.LoadChunk(chunkCol, chunkRow)
+	return chunk, col - chunkCol*m.chunkSize, row - chunkRow*m.chunkSize
+}
+
+func (m *ChunkedNavigationMap) unloadUnused() {
+	if m.maxChunkCount <= 0 || len(m.chunks) < m.maxChunkCount {
+		return
+	}
+
+	var oldestKey Grid
+	oldestUse := uint64(0)
+	bFound := false
+	for key, chunk := range m.chunks {
+		if !bFound || chunk.lastUse < oldestUse {
+			oldestKey = key
+			oldestUse = chunk.lastUse
+			bFound = true
+		}
+	}
+
+	delete(m.chunks, oldestKey)
+}
+
+// round down for the negative number
+func floorDiv(a int, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+
+	return q
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"sync"
+	"testing"
+)
+
+// an endless open world, the grid (0, 0) is walled in
+func newTestWalledWorld(maxChunkCount int) *ChunkedNavigationMap {
+	return NewChunkedNavigationMap(8, func(chunkCol int, chunkRow int) NavigationMap {
+		m := NewGridMap(8, 8, 1)
+		if chunkCol == 0 && chunkRow == 0 {
+			m.SetCanCross(1, 0, false)
+			m.SetCanCross(0, 1, false)
+			m.SetCanCross(1, 1, false)
+		}
+
+		if chunkCol == -1 && chunkRow == 0 {
+			m.SetCanCross(7, 0, false)
+			m.SetCanCross(7, 1, false)
+		}
+
+		if chunkCol == 0 && chunkRow == -1 {
+			m.SetCanCross(0, 7, false)
+			m.SetCanCross(1, 7, false)
+		}
+
+		if chunkCol == -1 && chunkRow == -1 {
+			m.SetCanCross(7, 7, false)
+		}
+
+		return m
+	}, 1, maxChunkCount)
+}
+
+func TestChunkedMapUnreachable(t *testing.T) {
+	finders := map[string]*BasePathFinder{
+		"astar": NewAStar().BasePathFinder,
+		"jps":   NewJps(0, false).BasePathFinder,
+		"jps8":  NewJps(0, true).BasePathFinder,
+	}
+
+	for name, finder := range finders {
+		m := newTestWalledWorld(16)
+		finder.SetMaxExpandedCount(500)
+		if _, ok := finder.FindPath(m, NewGrid(5, 5), NewGrid(0, 0)); ok {
+			t.Errorf("%s: path found to the walled grid", name)
+		}
+
+		if finder.GetStats().ExpandedCount > 500 {
+			t.Errorf("%s: expanded %d nodes, want at most 500", name, finder.GetStats().ExpandedCount)
+		}
+
+		if _, ok := finder.FindPath(m, NewGrid(5, 5), NewGrid(-5, 3)); !ok {
+			t.Errorf("%s: no path to the open grid", name)
+		}
+	}
+}
+
+func TestChunkedMapConcurrent(t *testing.T) {
+	m := newTestWalledWorld(4)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 1000; j++ {
+				col := (i*37 + j*13) % 200
+				if m.CanCross(col-100, j%64-32) != m.CanCross(col-100, j%64-32) {
+					t.Error("the grid changed")
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+	if m.GetLoadedChunkCount() > 4 {
+		t.Errorf("got %d loaded chunks, want at most 4", m.GetLoadedChunkCount())
+	}
+}
+
+func TestChunkedMapViews(t *testing.T) {
+	profile := NewMovementProfile("walk")
+	profile.SetTerrain(0, true, 100)
+	views := map[string]func(m *ChunkedNavigationMap) NavigationMap{
+		"edge layer": func(m *ChunkedNavigationMap) NavigationMap { return NewEdgeLayer(m) },
+		"link layer": func(m *ChunkedNavigationMap) NavigationMap { return NewLinkLayer(m) },
+		"terrain layer": func(m *ChunkedNavigationMap) NavigationMap {
+			l := NewTerrainLayer(m)
+			l.SetTerrain(-100, -100, 1)
+			return l
+		},
+		"profile map": func(m *ChunkedNavigationMap) NavigationMap {
+			// the terrain decides the walls of the profile map
+			l := NewTerrainLayer(m)
+			for _, vec := range ringVectors {
+				l.SetTerrain(vec.X, vec.Y, 1)
+			}
+
+			return NewProfileMap(l, profile)
+		},
+		"rect map": func(m *ChunkedNavigationMap) NavigationMap { return NewRectMap(m, -10, -10, 30, 30) },
+	}
+
+	for name, view := range views {
+		m := view(newTestWalledWorld(16))
+		if !isUnbounded(m) {
+			t.Errorf("%s: the view is bounded", name)
+		}
+
+		for _, finder := range []PathFinder{NewAStar(), NewJps(0, false), NewJps(0, true)} {
+			if _, ok := finder.FindPath(m, NewGrid(-3, -3), NewGrid(20, 5)); !ok {
+				t.Errorf("%s: no path", name)
+			}
+		}
+
+		// the scans of jps stop at the default deep, and AStar stops at the default count
+		jps := NewJps(0, false)
+		jps.SetMaxExpandedCount(1000)
+		for _, finder := range []PathFinder{NewAStar(), jps} {
+			if _, ok := finder.FindPath(m, NewGrid(5, 5), NewGrid(0, 0)); ok {
+				t.Errorf("%s: path found to the walled grid", name)
+			}
+		}
+
+		if _, ok := SnapToCrossable(m, NewGridTransform(0, 0, 1), NewWorldPos(-2.5, -2.5), 1); !ok {
+			t.Errorf("%s: can't snap to the grid of the negative position", name)
+		}
+	}
+}
+
+func TestChunkedMapBuilders(t *testing.T) {
+	builders := map[string]func(m NavigationMap){
+		"clearance map":   func(m NavigationMap) { NewClearanceMap(m) },
+		"component index": func(m NavigationMap) { NewComponentIndex(m, Connectivity4) },
+		"flow field":      func(m NavigationMap) { NewFlowField(m, Connectivity4) },
+		"hpa":             func(m NavigationMap) { NewHpa(m, 8, func() PathFinder { return NewAStar() }) },
+		"jps plus table":  func(m NavigationMap) { NewJpsPlusTable(m, false) },
+		"grid map":        func(m NavigationMap) { NewGridMapFrom(m) },
+	}
+
+	for name, build := range builders {
+		func() {
+			defer func() {
+				if err := recover(); err != ErrUnboundedMap {
+					t.Errorf("%s: got panic %v, want %v", name, err, ErrUnboundedMap)
+				}
+			}()
+
+			build(NewLinkLayer(newTestWalledWorld(16)))
+		}()
+	}
+}
@@ -0,0 +1,313 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "encoding/json"
+
+//========================
+//      ClearanceMap
+//========================
+// the clearance of a grid is the size of the largest square of crossable
+// grids which has the grid at the left top corner, and no wall of the edges
+// inside. an edge blocked in either direction is a wall for the square
+type ClearanceMap struct {
+	m          NavigationMap
+	cols       int
+	rows       int
+	clearances []uint32
+}
+
+// panic with ErrUnboundedMap if the map is unbounded
+func NewClearanceMap(m NavigationMap) *ClearanceMap {
+	checkBoundedMap(m)
+	c := &ClearanceMap{
+		m: m,
+	}
+
+	c.Build()
+	return c
+}
+
+func (c *ClearanceMap) Build() {
+	cols, rows := c.m.GetColRow()
+	c.cols = int(cols)
+	c.rows = int(rows)
+	c.clearances = make([]uint32, c.cols*c.rows)
+
+	for row := c.rows - 1; row >= 0; row-- {
+		for col := c.cols - 1; col >= 0; col-- {
+			c.clearances[row*c.cols+col] = c.calClearance(col, row)
+		}
+	}
+}
+
+func (c *ClearanceMap) GetClearance(col int, row int) uint32 {
+	if col < 0 || row < 0 || col >= c.cols || row >= c.rows {
+		return 0
+	}
+
+	return c.clearances[row*c.cols+col]
+}
+
+// whether an agent with the size can stand at the grid, the grid is the left top corner of the agent
+func (c *ClearanceMap) CanAgentCross(col int, row int, size uint32) bool {
+	return c.GetClearance(col, row) >= size
+}
+
+func (c *ClearanceMap) UpdateGrid(col int, row int) {
+	c.UpdateRect(col, row, col, row)
+}
+
+// recompute the clearances after the grids or the edges in the rect changed, only the grids
+// at the left top of the rect may change
+func (c *ClearanceMap) UpdateRect(left int, top int, right int, bottom int) {
+	// the square of the grid at the left top has the edges of the rect inside
+	left = maxInt(left-1, 0)
+	top = maxInt(top-1, 0)
+	right = minInt(right, c.cols-1)
+	bottom = minInt(bottom, c.rows-1)
+	if left > right || top > bottom {
+		return
+	}
+
+	// the changed columns of the row below
+	changedLeft := -1
+	changedRight := -1
+	for row := bottom; row >= 0; row-- {
+		dirtyLeft := changedLeft - 1
+		dirtyRight := changedRight
+		if changedLeft < 0 {
+			dirtyLeft = c.cols
+			dirtyRight = -1
+		}
+
+		if row >= top {
+			dirtyLeft = minInt(dirtyLeft, left)
+			dirtyRight = maxInt(dirtyRight, right)
+		}
+
+		if dirtyLeft > dirtyRight {
+			return
+		}
+
+		changedLeft = -1
+		changedRight = -1
+		for col := dirtyRight; col >= 0; col-- {
+			// the right grid not changed, no more change on the left
+			if col < dirtyLeft && changedLeft != col+1 {
+				break
+			}
+
+			idx := row*c.cols + col
+			clearance := c.calClearance(col, row)
+			if clearance == c.clearances[idx] {
+				continue
+			}
+
+			c.clearances[idx] = clearance
+			changedLeft = col
+			if changedRight < 0 {
+				changedRight = col
+			}
+		}
+	}
+}
+
+func (c *ClearanceMap) calClearance(col int, row int) uint32 {
+	if !c.m.CanCross(col, row) {
+		return 0
+	}
+
+	clearance := c.GetClearance(col+1, row)
+	if down := c.GetClearance(col, row+1); down < clearance {
+		clearance = down
+	}
+
+	if rightDown := c.GetClearance(col+1, row+1); rightDown < clearance {
+		clearance = rightDown
+	}
+
+	// the bigger squares have the 2x2 square inside, the other edges are
+	// inside the smaller squares at the right and the bottom
+	if clearance > 0 && c.hasWallInSquare(col, row) {
+		return 1
+	}
+
+	return clearance + 1
+}
+
+func (c *ClearanceMap) hasWallInSquare(col int, row int) bool {
+	return c.isWall(col, row, col+1, row) ||
+		c.isWall(col, row, col, row+1) ||
+		c.isWall(col+1, row, col+1, row+1) ||
+		c.isWall(col, row+1, col+1, row+1)
+}
+
+// the grids are crossable, so only the edges may block
+func (c *ClearanceMap) isWall(col1 int, row1 int, col2 int, row2 int) bool {
+	if !hasEdgeCost(c.m, col1, row1) && !hasEdgeCost(c.m, col2, row2) {
+		return false
+	}
+
+	_, ok1 := getEdgeGValue(c.m, col1, row1, col2, row2)
+	_, ok2 := getEdgeGValue(c.m, col2, row2, col1, row1)
+	return !ok1 || !ok2
+}
+
+func maxInt(a int, b int) int {
+	if a > b {
+		return a
+	}
+
+	return b
+}
+
+//========================
+//      AgentSizeMap
+//========================
+// a grid can be crossed only if the agent with the size fits in
+type AgentSizeMap struct {
+	NavigationMap
+	mapForwarder
+	clearanceMap *ClearanceMap
+	size         uint32
+}
+
+func NewAgentSizeMap(m NavigationMap, clearanceMap *ClearanceMap, size uint32) *AgentSizeMap {
+	return &AgentSizeMap{
+		NavigationMap: m,
+		mapForwarder:  mapForwarder{inner: m},
+		clearanceMap:  clearanceMap,
+		size:          size,
+	}
+}
+
+func (m *AgentSizeMap) GetAgentSize() uint32 {
+	return m.size
+}
+
+func (m *AgentSizeMap) CanCross(col int, row int) bool {
+	return m.clearanceMap.CanAgentCross(col, row, m.size)
+}
+
+func (m *AgentSizeMap) GetEdgeGValue(fromCol int, fromRow int, toCol int, toRow int) (uint32, bool) {
+	if !m.CanCross(toCol, toRow) {
+		return 0, false
+	}
+
+	return getEdgeGValue(m.NavigationMap, fromCol, fromRow, toCol, toRow)
+}
+
+//========================
+//   ClearanceMap Encoding
+//========================
+const (
+	clearanceMagic   = "NAVC"
+	clearanceVersion = 1
+)
+
+type clearanceJson struct {
+	Version    int      `json:"version"`
+	Cols       int      `json:"cols"`
+	Rows       int      `json:"rows"`
+	Clearances []uint32 `json:"clearances"`
+	Checksum   uint32   `json:"checksum"`
+}
+
+// load the clearances built before for the map, the data is the binary or the json encoding
+func LoadClearanceMap(m NavigationMap, data []byte) (*ClearanceMap, error) {
+	c := &ClearanceMap{
+		m: m,
+	}
+
+	err := unmarshalAny(data, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *ClearanceMap) MarshalBinary() ([]byte, error) {
+	w := newBinaryWriter(clearanceMagic, clearanceVersion)
+	w.putUvarint(uint64(c.cols))
+	w.putUvarint(uint64(c.rows))
+	for _, clearance := range c.clearances {
+		w.putUvarint(uint64(clearance))
+	}
+
+	return w.finish(), nil
+}
+
+func (c *ClearanceMap) UnmarshalBinary(data []byte) error {
+	r, err := newBinaryReader(data, clearanceMagic, clearanceVersion)
+	if err != nil {
+		return err
+	}
+
+	cols := r.getUvarint()
+	rows := r.getUvarint()
+	if r.err == nil && rows != 0 && cols > uint64(len(data))/rows {
+		return ErrEncodingBadSize
+	}
+
+	clearances := make([]uint32, int(cols*rows))
+	for i := range clearances {
+		clearances[i] = uint32(r.getUvarint())
+	}
+
+	if err := r.finish(); err != nil {
+		return err
+	}
+
+	return c.setClearances(int(cols), int(rows), clearances)
+}
+
+func (c *ClearanceMap) MarshalJSON() ([]byte, error) {
+	data, _ := c.MarshalBinary()
+	return json.Marshal(&clearanceJson{
+		Version:    clearanceVersion,
+		Cols:       c.cols,
+		Rows:       c.rows,
+		Clearances: c.clearances,
+		Checksum:   getChecksum(data),
+	})
+}
+
+func (c *ClearanceMap) UnmarshalJSON(data []byte) error {
+	j := &clearanceJson{}
+	err := json.Unmarshal(data, j)
+	if err != nil {
+		return err
+	}
+
+	if j.Version != clearanceVersion {
+		return ErrEncodingBadVersion
+	}
+
+	if j.Cols < 0 || j.Rows < 0 || len(j.Clearances) != j.Cols*j.Rows {
+		return ErrEncodingBadSize
+	}
+
+	decoded := &ClearanceMap{cols: j.Cols, rows: j.Rows, clearances: j.Clearances}
+	binaryData, _ := decoded.MarshalBinary()
+	if getChecksum(binaryData) != j.Checksum {
+		return ErrEncodingBadChecksum
+	}
+
+	return c.setClearances(j.Cols, j.Rows, j.Clearances)
+}
+
+func (c *ClearanceMap) setClearances(cols int, rows int, clearances []uint32) error {
+	err := checkMapSize(c.m, cols, rows)
+	if err != nil {
+		return err
+	}
+
+	c.cols = cols
+	c.rows = rows
+	c.clearances = clearances
+	return nil
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the largest square at the left top grid, checked grid by grid and edge by edge
+func getTestClearance(m NavigationMap, col int, row int) uint32 {
+	cols, rows := m.GetColRow()
+	size := 0
+	for col+size < int(cols) && row+size < int(rows) {
+		for i := 0; i <= size; i++ {
+			for _, grid := range []*Grid{NewGrid(col+size, row+i), NewGrid(col+i, row+size)} {
+				if !m.CanCross(grid.Col, grid.Row) {
+					return uint32(size)
+				}
+
+				// the edges to the grids of the smaller square
+				for _, prev := range []*Grid{NewGrid(grid.Col-1, grid.Row), NewGrid(grid.Col, grid.Row-1)} {
+					if prev.Col < col || prev.Row < row {
+						continue
+					}
+
+					_, ok1 := getEdgeGValue(m, prev.Col, prev.Row, grid.Col, grid.Row)
+					_, ok2 := getEdgeGValue(m, grid.Col, grid.Row, prev.Col, prev.Row)
+					if !ok1 || !ok2 {
+						return uint32(size)
+					}
+				}
+			}
+		}
+
+		size++
+	}
+
+	return uint32(size)
+}
+
+func checkClearanceMap(t *testing.T, name string, c *ClearanceMap, m NavigationMap) {
+	cols, rows := m.GetColRow()
+	for row := 0; row < int(rows); row++ {
+		for col := 0; col < int(cols); col++ {
+			if got, want := c.GetClearance(col, row), getTestClearance(m, col, row); got != want {
+				t.Fatalf("%s: got clearance %d at (%d, %d), want %d", name, got, col, row, want)
+			}
+		}
+	}
+}
+
+func TestClearanceMapEdges(t *testing.T) {
+	m := NewEdgeLayer(newTestMap(t, "....", "....", "....", "...."))
+	m.SetWall(NewGrid(1, 2), NewGrid(2, 2))
+	c := NewClearanceMap(m)
+	if clearance := c.GetClearance(0, 0); clearance != 2 {
+		t.Errorf("got clearance %d at (0, 0), want 2", clearance)
+	}
+
+	// the one-way edge is a wall for the square too
+	m.SetOneWay(NewGrid(0, 0), NewGrid(1, 0))
+	c.UpdateGrid(0, 0)
+	if clearance := c.GetClearance(0, 0); clearance != 1 {
+		t.Errorf("got clearance %d at (0, 0), want 1", clearance)
+	}
+
+	checkClearanceMap(t, "edges", c, m)
+}
+
+// the updates of the grids and the edges give the clearances of a full build
+func TestClearanceMapUpdateRect(t *testing.T) {
+	rnd := rand.New(rand.NewSource(31))
+	m := NewEdgeLayer(newRandomTestMap(31, 20, 20, 0.1, 1))
+	c := NewClearanceMap(m)
+	checkClearanceMap(t, "build", c, m)
+
+	for i := 0; i < 200; i++ {
+		col := rnd.Intn(19)
+		row := rnd.Intn(19)
+		right, bottom := col, row
+		switch rnd.Intn(4) {
+		case 0:
+			m.SetWall(NewGrid(col, row), NewGrid(col+1, row))
+			right++
+		case 1:
+			m.SetOneWay(NewGrid(col, row+1), NewGrid(col, row))
+			bottom++
+		case 2:
+			m.RemoveEdge(NewGrid(col, row), NewGrid(col+1, row))
+			m.RemoveEdge(NewGrid(col+1, row), NewGrid(col, row))
+			right++
+		default:
+			inner := m.NavigationMap.(*GridMap)
+			inner.SetCanCross(col, row, !inner.CanCross(col, row))
+		}
+
+		c.UpdateRect(col, row, right, bottom)
+		checkClearanceMap(t, "update", c, m)
+	}
+
+	checkClearanceMap(t, "rebuild", NewClearanceMap(m), m)
+}
+
+func TestAgentSizeMap(t *testing.T) {
+	m := NewEdgeLayer(newTestMap(t,
+		"......",
+		"......",
+		"......",
+		"......",
+		"##.###",
+		"......"))
+
+	// the thin wall between col 2 and col 3 at the top
+	m.SetWall(NewGrid(2, 0), NewGrid(3, 0))
+	m.SetWall(NewGrid(2, 1), NewGrid(3, 1))
+	c := NewClearanceMap(m)
+
+	finder := NewAStar()
+	finder.SetAgentSize(c, 2)
+	// down to the rows 2 - 3 around the wall, and back up
+	if gValue, ok := getPathGValue(t, finder, m, NewGrid(0, 0), NewGrid(4, 0)); !ok || gValue != 8 {
+		t.Errorf("got g value %d (%v), want 8", gValue, ok)
+	}
+
+	// the corridor is too narrow for the agent
+	if _, ok := finder.FindPath(m, NewGrid(0, 0), NewGrid(0, 5)); ok {
+		t.Error("path found through the narrow corridor")
+	}
+
+	finder.SetAgentSize(c, 1)
+	if gValue, ok := getPathGValue(t, finder, m, NewGrid(0, 0), NewGrid(0, 5)); !ok || gValue != 9 {
+		t.Errorf("size 1: got g value %d (%v), want 9", gValue, ok)
+	}
+
+	sizeMap := NewAgentSizeMap(m, c, 2)
+	if sizeMap.CanCross(2, 3) || !sizeMap.CanCross(2, 2) {
+		t.Error("the agent of size 2 doesn't fit the clearances")
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/yxlib/nav/bench"
+)
+
+const benchLineFormat = "%-24s %8s %14s %12s %10s\n"
+
+type benchResult struct {
+	Name        string  `json:"name"`
+	N           int     `json:"n"`
+	NsPerOp     int64   `json:"nsPerOp"`
+	BytesPerOp  int64   `json:"bytesPerOp"`
+	AllocsPerOp int64   `json:"allocsPerOp"`
+	Seconds     float64 `json:"seconds"`
+}
+
+func runBench(opts *options) error {
+	pattern, err := regexp.Compile(opts.bench)
+	if err != nil {
+		return err
+	}
+
+	suite := bench.NewSuite(opts.seed)
+	if !opts.bJson {
+		fmt.Printf("seed: %d\n", suite.GetSeed())
+		fmt.Printf(benchLineFormat, "name", "n", "ns/op", "B/op", "allocs/op")
+	}
+
+	results := make([]*benchResult, 0)
+	suite.Run(pattern.MatchString, func(name string, r testing.BenchmarkResult) {
+		res := &benchResult{
+			Name:        name,
+			N:           r.N,
+			NsPerOp:     r.NsPerOp(),
+			BytesPerOp:  r.AllocedBytesPerOp(),
+			AllocsPerOp: r.AllocsPerOp(),
+			Seconds:     r.T.Seconds(),
+		}
+
+		results = append(results, res)
+		// print each result at once, the whole suite takes minutes
+		if !opts.bJson {
+			fmt.Printf(benchLineFormat, res.Name, fmt.Sprint(res.N), fmt.Sprint(res.NsPerOp),
+				fmt.Sprint(res.BytesPerOp), fmt.Sprint(res.AllocsPerOp))
+		}
+	})
+
+	if opts.bJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	return nil
+}
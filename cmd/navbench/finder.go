@@ -0,0 +1,40 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+
+	"github.com/yxlib/nav"
+)
+
+var finderNames = []string{"astar", "dijkstra", "jps", "jps8", "jpsplus", "jpsplus8", "hpa"}
+
+// the finders are built before the queries, the preprocess time is not counted
+func newFinder(name string, m nav.NavigationMap, clusterSize int) (nav.PathFinder, error) {
+	switch name {
+	case "astar":
+		return nav.NewAStar(), nil
+	case "dijkstra":
+		// the search stops when the dest grid is popped, so it is dijkstra without the heuristic
+		a := nav.NewAStar()
+		a.SetHeuristic(nav.ZeroHeuristic)
+		return a, nil
+	case "jps":
+		return nav.NewJps(0, false), nil
+	case "jps8":
+		return nav.NewJps(0, true), nil
+	case "jpsplus":
+		return nav.NewJpsPlus(nav.NewJpsPlusTable(m, false)), nil
+	case "jpsplus8":
+		return nav.NewJpsPlus(nav.NewJpsPlusTable(m, true)), nil
+	case "hpa":
+		return nav.NewHpa(m, clusterSize, func() nav.PathFinder {
+			return nav.NewAStar()
+		}), nil
+	default:
+		return nil, fmt.Errorf("unknown finder %q, one of %v", name, finderNames)
+	}
+}
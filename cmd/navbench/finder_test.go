@@ -0,0 +1,38 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/yxlib/nav"
+)
+
+// the direct edge costs more than the way around
+func TestDijkstraEdgeCost(t *testing.T) {
+	grids, err := nav.LoadAsciiMap(strings.NewReader("...\n...\n..."))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := nav.NewEdgeLayer(grids)
+	m.SetEdge(nav.NewGrid(1, 1), nav.NewGrid(2, 1), 50, true)
+	for _, name := range []string{"astar", "dijkstra"} {
+		finder, err := newFinder(name, m, 8)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		fullPath, ok := finder.FindPath(m, nav.NewGrid(0, 1), nav.NewGrid(2, 1))
+		if !ok {
+			t.Fatalf("%s: no path", name)
+		}
+
+		if gValue := fullPath[len(fullPath)-1].GetMinGValue(); gValue != 4 {
+			t.Errorf("%s: got g value %d, want 4", name, gValue)
+		}
+	}
+}
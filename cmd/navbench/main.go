@@ -0,0 +1,307 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// navbench runs the finders on a map and prints the timing and the search stats.
+//
+//	navbench -map arena.map -start 3,4 -dst 40,20 -finder astar,jps -render
+//	navbench -map arena.map -scen arena.map.scen -finder jps8,jpsplus8 -json
+//	navbench -bench 'jps/maze/.*' -seed 20220601
+//
+// the map format is chosen by the extension, .map for MovingAI, .png for png,
+// and ascii for the others.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yxlib/nav"
+	"github.com/yxlib/nav/bench"
+)
+
+type options struct {
+	mapPath     string
+	scenPath    string
+	finders     string
+	start       string
+	dst         string
+	repeat      int
+	clusterSize int
+	bench       string
+	seed        int64
+	bRender     bool
+	bJson       bool
+}
+
+// the result of a query by a finder
+type result struct {
+	Finder         string   `json:"finder"`
+	Start          nav.Grid `json:"start"`
+	Dst            nav.Grid `json:"dst"`
+	Found          bool     `json:"found"`
+	PathLength     int      `json:"pathLength"`
+	PathGValue     uint32   `json:"pathGValue"`
+	OptimalLength  float64  `json:"optimalLength,omitempty"`
+	ExpandedCount  int      `json:"expanded"`
+	GeneratedCount int      `json:"generated"`
+	PeakOpenCount  int      `json:"peakOpen"`
+	JumpScanLength int      `json:"jumpScan"`
+	ElapsedMicros  float64  `json:"elapsedMicros"`
+}
+
+type query struct {
+	startGrid     nav.Grid
+	dstGrid       nav.Grid
+	optimalLength float64
+}
+
+type statsFinder interface {
+	GetStats() *nav.SearchStats
+}
+
+func main() {
+	opts := &options{}
+	flag.StringVar(&opts.mapPath, "map", "", "the map file, ascii, MovingAI (.map) or png (.png)")
+	flag.StringVar(&opts.scenPath, "scen", "", "the MovingAI scenario file, replace -start and -dst")
+	flag.StringVar(&opts.finders, "finder", "astar", "the finders separated by comma: "+strings.Join(finderNames, ", "))
+	flag.StringVar(&opts.start, "start", "", "the start grid, col,row")
+	flag.StringVar(&opts.dst, "dst", "", "the dest grid, col,row")
+	flag.IntVar(&opts.repeat, "repeat", 1, "run each query the times, the elapsed time is the average")
+	flag.IntVar(&opts.clusterSize, "cluster", 16, "the cluster size of hpa")
+	flag.BoolVar(&opts.bRender, "render", false, "draw the path of the single query")
+	flag.BoolVar(&opts.bJson, "json", false, "output json instead of a table")
+	flag.StringVar(&opts.bench, "bench", "", "run the generated benchmarks matching the regexp, named as finder/family/size")
+	flag.Int64Var(&opts.seed, "seed", bench.DefaultSeed, "the seed of the generated benchmarks")
+	flag.Parse()
+
+	if opts.bench != "" {
+		if err := runBench(opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
+	if opts.mapPath == "" || (opts.scenPath == "" && (opts.start == "" || opts.dst == "")) {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(opts *options) error {
+	m, err := loadMap(opts.mapPath)
+	if err != nil {
+		return err
+	}
+
+	queries, err := loadQueries(opts)
+	if err != nil {
+		return err
+	}
+
+	if opts.repeat < 1 {
+		opts.repeat = 1
+	}
+
+	results := make([]*result, 0)
+	for _, name := range strings.Split(opts.finders, ",") {
+		name = strings.TrimSpace(name)
+		finder, err := newFinder(name, m, opts.clusterSize)
+		if err != nil {
+			return err
+		}
+
+		for _, q := range queries {
+			res, fullPath := runQuery(finder, name, m, q, opts.repeat)
+			results = append(results, res)
+			if opts.bRender && len(queries) == 1 && !opts.bJson {
+				fmt.Printf("%s:\n", name)
+				fmt.Print(render(m, &q.startGrid, &q.dstGrid, fullPath))
+			}
+		}
+	}
+
+	if opts.bJson {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(results)
+	}
+
+	printTable(results)
+	return nil
+}
+
+func loadMap(path string) (*nav.GridMap, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".map":
+		return nav.LoadMovingAIMap(file)
+	case ".png":
+		return nav.LoadPngMap(file)
+	default:
+		return nav.LoadAsciiMap(file)
+	}
+}
+
+func loadQueries(opts *options) ([]*query, error) {
+	if opts.scenPath == "" {
+		startGrid, err := parseGrid(opts.start)
+		if err != nil {
+			return nil, err
+		}
+
+		dstGrid, err := parseGrid(opts.dst)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*query{{startGrid: startGrid, dstGrid: dstGrid}}, nil
+	}
+
+	file, err := os.Open(opts.scenPath)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	scenarios, err := nav.LoadMovingAIScenarios(file)
+	if err != nil {
+		return nil, err
+	}
+
+	queries := make([]*query, 0, len(scenarios))
+	for _, scenario := range scenarios {
+		queries = append(queries, &query{
+			startGrid:     scenario.StartGrid,
+			dstGrid:       scenario.DstGrid,
+			optimalLength: scenario.OptimalLength,
+		})
+	}
+
+	return queries, nil
+}
+
+func parseGrid(s string) (nav.Grid, error) {
+	grid := nav.Grid{}
+	_, err := fmt.Sscanf(s, "%d,%d", &grid.Col, &grid.Row)
+	if err != nil {
+		return grid, fmt.Errorf("bad grid %q, col,row expected", s)
+	}
+
+	return grid, nil
+}
+
+func runQuery(finder nav.PathFinder, name string, m nav.NavigationMap, q *query, repeat int) (*result, []nav.PathNode) {
+	var fullPath []nav.PathNode
+	var ok bool
+	begin := time.Now()
+	for i := 0; i < repeat; i++ {
+		fullPath, ok = finder.FindPath(m, &q.startGrid, &q.dstGrid)
+	}
+
+	elapsed := time.Since(begin) / time.Duration(repeat)
+	res := &result{
+		Finder:        name,
+		Start:         q.startGrid,
+		Dst:           q.dstGrid,
+		Found:         ok,
+		PathLength:    len(fullPath),
+		OptimalLength: q.optimalLength,
+		ElapsedMicros: float64(elapsed.Nanoseconds()) / 1000,
+	}
+
+	if len(fullPath) > 0 {
+		res.PathGValue = fullPath[len(fullPath)-1].GetMinGValue()
+	}
+
+	// hpa has no stats, only the time is reported
+	if f, ok := finder.(statsFinder); ok {
+		stats := f.GetStats()
+		res.ExpandedCount = stats.ExpandedCount
+		res.GeneratedCount = stats.GeneratedCount
+		res.PeakOpenCount = stats.PeakOpenCount
+		res.JumpScanLength = stats.JumpScanLength
+	}
+
+	// the nodes are reused by the next search
+	return res, nav.ClonePath(fullPath)
+}
+
+func printTable(results []*result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "finder\tstart\tdst\tfound\tnodes\tg\texpanded\tgenerated\tpeak open\tjump scan\tus\t")
+	for _, res := range results {
+		fmt.Fprintf(w, "%s\t%d,%d\t%d,%d\t%v\t%d\t%d\t%d\t%d\t%d\t%d\t%.1f\t\n", res.Finder,
+			res.Start.Col, res.Start.Row, res.Dst.Col, res.Dst.Row, res.Found, res.PathLength, res.PathGValue,
+			res.ExpandedCount, res.GeneratedCount, res.PeakOpenCount, res.JumpScanLength, res.ElapsedMicros)
+	}
+
+	w.Flush()
+}
+
+// 'S' start, 'D' dest, '*' path, '#' can't cross, '.' others, the grids between
+// the jump points are filled
+func render(m nav.NavigationMap, startGrid *nav.Grid, dstGrid *nav.Grid, fullPath []nav.PathNode) string {
+	path := make(map[nav.Grid]bool)
+	for i := 1; i < len(fullPath); i++ {
+		from := *fullPath[i-1].GetGrid()
+		to := fullPath[i].GetGrid()
+		for !from.IsSameGrid(to) {
+			path[from] = true
+			from.Col += sign(to.Col - from.Col)
+			from.Row += sign(to.Row - from.Row)
+		}
+	}
+
+	cols, rows := m.GetColRow()
+	var sb strings.Builder
+	for row := 0; row < int(rows); row++ {
+		for col := 0; col < int(cols); col++ {
+			grid := nav.Grid{Col: col, Row: row}
+			switch {
+			case startGrid.IsSameGrid(&grid):
+				sb.WriteByte('S')
+			case dstGrid.IsSameGrid(&grid):
+				sb.WriteByte('D')
+			case path[grid]:
+				sb.WriteByte('*')
+			case !m.CanCross(col, row):
+				sb.WriteByte('#')
+			default:
+				sb.WriteByte('.')
+			}
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+func sign(v int) int {
+	if v > 0 {
+		return 1
+	} else if v < 0 {
+		return -1
+	}
+
+	return 0
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// navtrace replays a search trace recorded by nav.TraceRecorder.
+//
+//	navtrace -trace route.json             step by pressing enter
+//	navtrace -trace route.json -step 120   print the state after 120 events
+//	navtrace -trace route.json -step -1    print the final state
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yxlib/nav"
+)
+
+func main() {
+	tracePath := flag.String("trace", "", "the trace file")
+	step := flag.Int("step", 0, "print the state after the count of events, -1 for the end, 0 to step interactively")
+	flag.Parse()
+
+	if *tracePath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	trace, err := nav.LoadTraceFile(*tracePath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	printHeader(trace)
+	replayer := nav.NewTraceReplayer(trace)
+	if *step != 0 {
+		if *step < 0 {
+			*step = replayer.GetStepCount()
+		}
+
+		replayer.StepTo(*step)
+		printState(replayer, nil)
+		return
+	}
+
+	// enter for the next event, a number to go to the step, q to quit
+	reader := bufio.NewScanner(os.Stdin)
+	printState(replayer, nil)
+	for reader.Scan() {
+		line := reader.Text()
+		if line == "q" {
+			return
+		}
+
+		var target int
+		if _, err := fmt.Sscanf(line, "%d", &target); err == nil {
+			replayer.StepTo(target)
+			printState(replayer, nil)
+			continue
+		}
+
+		event, ok := replayer.Step()
+		if !ok {
+			fmt.Println("end of trace")
+			return
+		}
+
+		printState(replayer, event)
+	}
+}
+
+func printHeader(trace *nav.SearchTrace) {
+	fmt.Printf("finder: %s %v\n", trace.Finder, trace.Config)
+	fmt.Printf("map: %dx%d, start: (%d,%d), dest: (%d,%d)\n", trace.Map.Cols, trace.Map.Rows,
+		trace.Start.Col, trace.Start.Row, trace.Dst.Col, trace.Dst.Row)
+	fmt.Printf("found: %v, expanded: %d, generated: %d, path g: %d\n", trace.Stats.Found,
+		trace.Stats.ExpandedCount, trace.Stats.GeneratedCount, trace.Stats.PathGValue)
+}
+
+func printState(replayer *nav.TraceReplayer, event *nav.TraceEvent) {
+	fmt.Printf("step %d/%d", replayer.GetStep(), replayer.GetStepCount())
+	if event != nil {
+		fmt.Printf(", %s (%d,%d) g=%d", event.Type, event.Col, event.Row, event.GValue)
+		if event.HasParent {
+			fmt.Printf(" from (%d,%d)", event.ParentCol, event.ParentRow)
+		}
+	}
+
+	fmt.Println()
+	fmt.Print(replayer.Render())
+}
@@ -0,0 +1,564 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"encoding/json"
+	"math"
+)
+
+//========================
+//      Connectivity
+//========================
+type Connectivity int
+
+const (
+	// move to the 4 orthogonal neighbours, the same as AStar
+	Connectivity4 Connectivity = iota
+	// also move obliquely through one of the side grids, it costs the same as the two
+	// orthogonal moves, the same as Jps without oblique move
+	Connectivity8
+	// also move obliquely even if both side grids can't be crossed, it costs the g value
+	// of the dest grid, the same as Jps with oblique move
+	Connectivity8CornerCut
+)
+
+// the 8 neighbours in clockwise order
+var ringVectors = []*Vector{
+	VecUp,
+	VecRightUp,
+	VecRight,
+	VecRightDown,
+	VecDown,
+	VecLeftDown,
+	VecLeft,
+	VecLeftUp,
+}
+
+// the vectors of one move
+func getMoveVectors(connectivity Connectivity) []*Vector {
+	if connectivity == Connectivity4 {
+		return []*Vector{VecUp, VecRight, VecDown, VecLeft}
+	}
+
+	return ringVectors
+}
+
+// the g value of moving from the grid along the vector
+func getMoveGValue(m NavigationMap, connectivity Connectivity, col int, row int, vec *Vector) (uint32, bool) {
+	if !m.CanCross(col, row) {
+		return 0, false
+	}
+
+	if vec.IsOblique() {
+		if connectivity == Connectivity4 {
+			return 0, false
+		}
+
+		if connectivity == Connectivity8 {
+			return getObliqueGValueBySide(m, col, row, col+vec.X, row+vec.Y)
+		}
+	}
+
+	return getEdgeGValue(m, col, row, col+vec.X, row+vec.Y)
+}
+
+//========================
+//     ComponentIndex
+//========================
+// label the connected regions of a map, two grids are reachable from each other
+// only if they have the same label, or their labels are joined by the links of
+// the map. build it again after the links of the map changed
+type ComponentIndex struct {
+	m            NavigationMap
+	connectivity Connectivity
+	cols         int
+	rows         int
+	labels       []uint32
+	sizes        map[uint32]int
+	nextLabel    uint32
+	links        []*Link
+	linkGroups   map[uint32]uint32
+}
+
+// panic with ErrUnboundedMap if the map is unbounded
+func NewComponentIndex(m NavigationMap, connectivity Connectivity) *ComponentIndex {
+	checkBoundedMap(m)
+	c := &ComponentIndex{
+		m:            m,
+		connectivity: connectivity,
+	}
+
+	c.Build()
+	return c
+}
+
+func (c *ComponentIndex) Build() {
+	cols, rows := c.m.GetColRow()
+	c.cols = int(cols)
+	c.rows = int(rows)
+	c.labels = make([]uint32, c.cols*c.rows)
+	c.sizes = make(map[uint32]int)
+	c.nextLabel = 0
+
+	for row := 0; row < c.rows; row++ {
+		for col := 0; col < c.cols; col++ {
+			if c.labels[row*c.cols+col] == 0 && c.m.CanCross(col, row) {
+				label := c.newLabel()
+				c.sizes[label] = c.fill(col, row, label)
+			}
+		}
+	}
+
+	c.collectLinks()
+}
+
+func (c *ComponentIndex) GetConnectivity() Connectivity {
+	return c.connectivity
+}
+
+// label 0 means the grid can't be crossed
+func (c *ComponentIndex) GetComponent(col int, row int) uint32 {
+	if !c.isInMap(col, row) {
+		return 0
+	}
+
+	return c.labels[row*c.cols+col]
+}
+
+func (c *ComponentIndex) GetComponentSize(label uint32) int {
+	return c.sizes[label]
+}
+
+func (c *ComponentIndex) IsReachable(startGrid *Grid, dstGrid *Grid) bool {
+	label := c.GetComponent(startGrid.Col, startGrid.Row)
+	if label == 0 {
+		return false
+	}
+
+	return c.getLinkGroup(label) == c.getLinkGroup(c.GetComponent(dstGrid.Col, dstGrid.Row))
+}
+
+// update the labels after the crossable state of the grid changed
+func (c *ComponentIndex) UpdateGrid(col int, row int) {
+	if !c.isInMap(col, row) {
+		return
+	}
+
+	idx := row*c.cols + col
+	bCanCross := c.m.CanCross(col, row)
+	oldLabel := c.labels[idx]
+	if bCanCross == (oldLabel != 0) {
+		return
+	}
+
+	if bCanCross {
+		c.addGrid(col, row)
+	} else {
+		c.removeGrid(col, row)
+	}
+
+	// the labels are changed, join them again
+	if len(c.links) > 0 {
+		c.joinLinks()
+	}
+}
+
+// the links of the map may go between the components
+func (c *ComponentIndex) collectLinks() {
+	c.links = c.links[:0]
+	for row := 0; row < c.rows; row++ {
+		for col := 0; col < c.cols; col++ {
+			c.links = append(c.links, getLinks(c.m, col, row)...)
+		}
+	}
+
+	c.joinLinks()
+}
+
+// join the components of both ends of the links, the links are taken as two way,
+// so the precheck never fails a reachable grid
+func (c *ComponentIndex) joinLinks() {
+	c.linkGroups = make(map[uint32]uint32)
+	for _, link := range c.links {
+		from := c.getLinkGroup(c.GetComponent(link.From.Col, link.From.Row))
+		to := c.getLinkGroup(c.GetComponent(link.To.Col, link.To.Row))
+		if from != 0 && to != 0 && from != to {
+			c.linkGroups[from] = to
+		}
+	}
+}
+
+func (c *ComponentIndex) getLinkGroup(label uint32) uint32 {
+	for {
+		parent, ok := c.linkGroups[label]
+		if !ok {
+			return label
+		}
+
+		label = parent
+	}
+}
+
+func (c *ComponentIndex) addGrid(col int, row int) {
+	// merge all the neighbour components into the biggest one
+	labels := make([]uint32, 0)
+	for _, vec := range c.getNeighbourVectors() {
+		label := c.GetComponent(col+vec.X, row+vec.Y)
+		if label != 0 && !containsLabel(labels, label) {
+			labels = append(labels, label)
+		}
+	}
+
+	if len(labels) == 0 {
+		label := c.newLabel()
+		c.labels[row*c.cols+col] = label
+		c.sizes[label] = 1
+		return
+	}
+
+	maxLabel := labels[0]
+	for _, label := range labels {
+		if c.sizes[label] > c.sizes[maxLabel] {
+			maxLabel = label
+		}
+	}
+
+	c.labels[row*c.cols+col] = maxLabel
+	c.sizes[maxLabel]++
+	for _, vec := range c.getNeighbourVectors() {
+		nextCol := col + vec.X
+		nextRow := row + vec.Y
+		label := c.GetComponent(nextCol, nextRow)
+		if label != 0 && label != maxLabel {
+			delete(c.sizes, label)
+			c.sizes[maxLabel] += c.fill(nextCol, nextRow, maxLabel)
+		}
+	}
+}
+
+func (c *ComponentIndex) removeGrid(col int, row int) {
+	idx := row*c.cols + col
+	oldLabel := c.labels[idx]
+	c.labels[idx] = 0
+	c.sizes[oldLabel]--
+	if c.sizes[oldLabel] == 0 {
+		delete(c.sizes, oldLabel)
+		return
+	}
+
+	// the neighbours are still connected around the grid, no split
+	if !c.canSplit(col, row) {
+		return
+	}
+
+	// clear the old component, then fill it again from the neighbours
+	neighbours := make([]*Grid, 0)
+	for _, vec := range c.getNeighbourVectors() {
+		nextCol := col + vec.X
+		nextRow := row + vec.Y
+		if c.GetComponent(nextCol, nextRow) == oldLabel {
+			neighbours = append(neighbours, NewGrid(nextCol, nextRow))
+			c.clearLabel(nextCol, nextRow, oldLabel)
+		}
+	}
+
+	// the first part keeps the old label
+	delete(c.sizes, oldLabel)
+	bFirst := true
+	for _, grid := range neighbours {
+		if c.labels[grid.Row*c.cols+grid.Col] != 0 {
+			continue
+		}
+
+		label := oldLabel
+		if !bFirst {
+			label = c.newLabel()
+		}
+
+		bFirst = false
+		c.sizes[label] = c.fill(grid.Col, grid.Row, label)
+	}
+}
+
+// check the crossable grids around, return false if they are linked without the center grid
+func (c *ComponentIndex) canSplit(col int, row int) bool {
+	ringCount := len(ringVectors)
+	bCross := make([]bool, ringCount)
+	for i, vec := range ringVectors {
+		bCross[i] = c.isInMap(col+vec.X, row+vec.Y) && c.m.CanCross(col+vec.X, row+vec.Y)
+	}
+
+	// union the ring grids which are linked directly
+	groups := make([]int, ringCount)
+	for i := range groups {
+		groups[i] = i
+	}
+
+	for i := 0; i < ringCount; i++ {
+		for k := i + 1; k < ringCount; k++ {
+			if bCross[i] && bCross[k] && c.isLinked(ringVectors[i], ringVectors[k]) {
+				mergeGroup(groups, i, k)
+			}
+		}
+	}
+
+	// the grids linked with the center grid should be in one group
+	group := -1
+	for _, vec := range c.getNeighbourVectors() {
+		i := indexOfVector(ringVectors, vec)
+		if !bCross[i] {
+			continue
+		}
+
+		g := findGroup(groups, i)
+		if group >= 0 && group != g {
+			return true
+		}
+
+		group = g
+	}
+
+	return false
+}
+
+func (c *ComponentIndex) isLinked(vec1 *Vector, vec2 *Vector) bool {
+	dx := absInt(vec1.X - vec2.X)
+	dy := absInt(vec1.Y - vec2.Y)
+	if c.connectivity == Connectivity8CornerCut {
+		return dx <= 1 && dy <= 1
+	}
+
+	return dx+dy == 1
+}
+
+// the oblique move with a crossable side grid is the same as two orthogonal moves,
+// so only the corner cut connectivity links the oblique neighbours
+func (c *ComponentIndex) getNeighbourVectors() []*Vector {
+	if c.connectivity == Connectivity8CornerCut {
+		return ringVectors
+	}
+
+	return []*Vector{VecUp, VecRight, VecDown, VecLeft}
+}
+
+func (c *ComponentIndex) fill(col int, row int, label uint32) int {
+	count := 0
+	stack := []*Grid{NewGrid(col, row)}
+	c.labels[row*c.cols+col] = label
+	for len(stack) > 0 {
+		grid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		count++
+
+		for _, vec := range c.getNeighbourVectors() {
+			nextCol := grid.Col + vec.X
+			nextRow := grid.Row + vec.Y
+			if !c.isInMap(nextCol, nextRow) {
+				continue
+			}
+
+			idx := nextRow*c.cols + nextCol
+			if c.labels[idx] == label || !c.m.CanCross(nextCol, nextRow) {
+				continue
+			}
+
+			c.labels[idx] = label
+			stack = append(stack, NewGrid(nextCol, nextRow))
+		}
+	}
+
+	return count
+}
+
+// clear the label of a region, so it can be filled again
+func (c *ComponentIndex) clearLabel(col int, row int, label uint32) {
+	c.labels[row*c.cols+col] = 0
+	stack := []*Grid{NewGrid(col, row)}
+	for len(stack) > 0 {
+		grid := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		for _, vec := range c.getNeighbourVectors() {
+			nextCol := grid.Col + vec.X
+			nextRow := grid.Row + vec.Y
+			if c.GetComponent(nextCol, nextRow) == label {
+				c.labels[nextRow*c.cols+nextCol] = 0
+				stack = append(stack, NewGrid(nextCol, nextRow))
+			}
+		}
+	}
+}
+
+func (c *ComponentIndex) newLabel() uint32 {
+	c.nextLabel++
+	return c.nextLabel
+}
+
+func (c *ComponentIndex) isInMap(col int, row int) bool {
+	return col >= 0 && row >= 0 && col < c.cols && row < c.rows
+}
+
+func containsLabel(labels []uint32, label uint32) bool {
+	for _, exist := range labels {
+		if exist == label {
+			return true
+		}
+	}
+
+	return false
+}
+
+func indexOfVector(vectors []*Vector, vec *Vector) int {
+	for i, exist := range vectors {
+		if exist == vec {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func findGroup(groups []int, i int) int {
+	for groups[i] != i {
+		i = groups[i]
+	}
+
+	return i
+}
+
+func mergeGroup(groups []int, i int, k int) {
+	groups[findGroup(groups, i)] = findGroup(groups, k)
+}
+
+//========================
+//  ComponentIndex Encoding
+//========================
+const (
+	componentIndexMagic   = "NAVI"
+	componentIndexVersion = 1
+)
+
+// the labels are the runs of [label, count]
+type componentIndexJson struct {
+	Version      int          `json:"version"`
+	Connectivity Connectivity `json:"connectivity"`
+	Cols         int          `json:"cols"`
+	Rows         int          `json:"rows"`
+	NextLabel    uint32       `json:"nextLabel"`
+	Labels       [][2]uint32  `json:"labels"`
+	Checksum     uint32       `json:"checksum"`
+}
+
+// load the labels built before for the map, the data is the binary or the json encoding
+func LoadComponentIndex(m NavigationMap, data []byte) (*ComponentIndex, error) {
+	c := &ComponentIndex{
+		m: m,
+	}
+
+	err := unmarshalAny(data, c)
+	if err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *ComponentIndex) MarshalBinary() ([]byte, error) {
+	w := newBinaryWriter(componentIndexMagic, componentIndexVersion)
+	w.putByte(byte(c.connectivity))
+	w.putUvarint(uint64(c.cols))
+	w.putUvarint(uint64(c.rows))
+	w.putUvarint(uint64(c.nextLabel))
+	w.putValueRuns(c.labels)
+	return w.finish(), nil
+}
+
+func (c *ComponentIndex) UnmarshalBinary(data []byte) error {
+	r, err := newBinaryReader(data, componentIndexMagic, componentIndexVersion)
+	if err != nil {
+		return err
+	}
+
+	connectivity := Connectivity(r.getByte())
+	cols := r.getUvarint()
+	rows := r.getUvarint()
+	nextLabel := uint32(r.getUvarint())
+	if cols > math.MaxInt32 || rows > math.MaxInt32 {
+		return ErrEncodingBadSize
+	}
+
+	labels := r.getValueRuns(int(cols * rows))
+	if err := r.finish(); err != nil {
+		return err
+	}
+
+	return c.setLabels(connectivity, int(cols), int(rows), nextLabel, labels)
+}
+
+func (c *ComponentIndex) MarshalJSON() ([]byte, error) {
+	data, _ := c.MarshalBinary()
+	return json.Marshal(&componentIndexJson{
+		Version:      componentIndexVersion,
+		Connectivity: c.connectivity,
+		Cols:         c.cols,
+		Rows:         c.rows,
+		NextLabel:    c.nextLabel,
+		Labels:       getValueRuns(c.labels),
+		Checksum:     getChecksum(data),
+	})
+}
+
+func (c *ComponentIndex) UnmarshalJSON(data []byte) error {
+	j := &componentIndexJson{}
+	err := json.Unmarshal(data, j)
+	if err != nil {
+		return err
+	}
+
+	if j.Version != componentIndexVersion {
+		return ErrEncodingBadVersion
+	}
+
+	if j.Cols < 0 || j.Rows < 0 {
+		return ErrEncodingBadSize
+	}
+
+	labels, ok := newValuesFromRuns(j.Labels, j.Cols*j.Rows)
+	if !ok {
+		return ErrEncodingBadSize
+	}
+
+	decoded := &ComponentIndex{connectivity: j.Connectivity, cols: j.Cols, rows: j.Rows, nextLabel: j.NextLabel, labels: labels}
+	binaryData, _ := decoded.MarshalBinary()
+	if getChecksum(binaryData) != j.Checksum {
+		return ErrEncodingBadChecksum
+	}
+
+	return c.setLabels(j.Connectivity, j.Cols, j.Rows, j.NextLabel, labels)
+}
+
+// the sizes of the components are counted from the labels
+func (c *ComponentIndex) setLabels(connectivity Connectivity, cols int, rows int, nextLabel uint32, labels []uint32) error {
+	err := checkMapSize(c.m, cols, rows)
+	if err != nil {
+		return err
+	}
+
+	c.connectivity = connectivity
+	c.cols = cols
+	c.rows = rows
+	c.nextLabel = nextLabel
+	c.labels = labels
+	c.sizes = make(map[uint32]int)
+	for _, label := range labels {
+		if label != 0 {
+			c.sizes[label]++
+		}
+	}
+
+	c.collectLinks()
+	return nil
+}
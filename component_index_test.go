@@ -0,0 +1,43 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+func TestComponentIndexLinks(t *testing.T) {
+	grids := newTestMap(t, "..#..", "..#..", "..#..")
+	m := NewLinkLayer(grids)
+	m.AddLink(NewGrid(0, 0), NewGrid(4, 0), 5, LinkTypeTeleport, false)
+	c := NewComponentIndex(m, Connectivity4)
+
+	startGrid := NewGrid(0, 2)
+	dstGrid := NewGrid(4, 2)
+	if c.GetComponent(startGrid.Col, startGrid.Row) == c.GetComponent(dstGrid.Col, dstGrid.Row) {
+		t.Fatalf("the sides of the wall have the same label")
+	}
+
+	if !c.IsReachable(startGrid, dstGrid) {
+		t.Errorf("the grids joined by the link are not reachable")
+	}
+
+	finder := NewAStar()
+	finder.SetComponentIndex(c)
+	if _, ok := finder.FindPath(m, startGrid, dstGrid); !ok {
+		t.Errorf("the precheck fails the path through the link")
+	}
+
+	// the link is still there after the labels change
+	grids.SetCanCross(0, 1, false)
+	c.UpdateGrid(0, 1)
+	if !c.IsReachable(startGrid, dstGrid) {
+		t.Errorf("the grids joined by the link are not reachable after the update")
+	}
+
+	grids.SetCanCross(1, 1, false)
+	c.UpdateGrid(1, 1)
+	if c.IsReachable(startGrid, dstGrid) {
+		t.Errorf("the grids split from the link are reachable")
+	}
+}
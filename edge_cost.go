@@ -0,0 +1,151 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+//========================
+//      EdgeCostMap
+//========================
+// optional for a navigation map, the cost of moving between adjacent grids,
+// GetMinGValue should not be greater than any edge g value
+type EdgeCostMap interface {
+	NavigationMap
+	// the g value of moving from a grid to an adjacent grid, return false if can't move
+	GetEdgeGValue(fromCol int, fromRow int, toCol int, toRow int) (uint32, bool)
+	// whether any edge of the grid is different from the default one
+	HasEdgeCost(col int, row int) bool
+}
+
+// the g value of moving to an adjacent grid, the default one is the g value of the dest grid
+func getEdgeGValue(m NavigationMap, fromCol int, fromRow int, toCol int, toRow int) (uint32, bool) {
+	edgeCostMap, ok := m.(EdgeCostMap)
+	if ok {
+		return edgeCostMap.GetEdgeGValue(fromCol, fromRow, toCol, toRow)
+	}
+
+	if !m.CanCross(toCol, toRow) {
+		return 0, false
+	}
+
+	return m.GetGValue(toCol, toRow), true
+}
+
+func hasEdgeCost(m NavigationMap, col int, row int) bool {
+	edgeCostMap, ok := m.(EdgeCostMap)
+	if !ok {
+		return false
+	}
+
+	return edgeCostMap.HasEdgeCost(col, row)
+}
+
+//========================
+//      EdgeLayer
+//========================
+type gridEdge struct {
+	from Grid
+	to   Grid
+}
+
+type edgeRule struct {
+	gValue    uint32
+	bCanCross bool
+}
+
+// add one-way grids, walls between grids or directional costs to a navigation map
+type EdgeLayer struct {
+	NavigationMap
+	mapForwarder
+	rules         map[gridEdge]*edgeRule
+	edgeCounts    map[Grid]int
+	minEdgeGValue uint32
+	bHasMin       bool
+}
+
+func NewEdgeLayer(m NavigationMap) *EdgeLayer {
+	return &EdgeLayer{
+		NavigationMap: m,
+		mapForwarder:  mapForwarder{inner: m},
+		rules:         make(map[gridEdge]*edgeRule),
+		edgeCounts:    make(map[Grid]int),
+	}
+}
+
+// set the g value of moving from a grid to the adjacent grid, the reverse edge is not changed
+func (l *EdgeLayer) SetEdge(from *Grid, to *Grid, gValue uint32, bCanCross bool) {
+	edge := gridEdge{from: *from, to: *to}
+	_, ok := l.rules[edge]
+	if !ok {
+		l.edgeCounts[edge.from]++
+		l.edgeCounts[edge.to]++
+	}
+
+	l.rules[edge] = &edgeRule{
+		gValue:    gValue,
+		bCanCross: bCanCross,
+	}
+
+	if bCanCross && (!l.bHasMin || gValue < l.minEdgeGValue) {
+		l.minEdgeGValue = gValue
+		l.bHasMin = true
+	}
+}
+
+// block both directions between two adjacent grids
+func (l *EdgeLayer) SetWall(grid1 *Grid, grid2 *Grid) {
+	l.SetEdge(grid1, grid2, 0, false)
+	l.SetEdge(grid2, grid1, 0, false)
+}
+
+// only can move from the grid to the adjacent grid, not the reverse
+func (l *EdgeLayer) SetOneWay(from *Grid, to *Grid) {
+	l.RemoveEdge(from, to)
+	l.SetEdge(to, from, 0, false)
+}
+
+func (l *EdgeLayer) RemoveEdge(from *Grid, to *Grid) {
+	edge := gridEdge{from: *from, to: *to}
+	_, ok := l.rules[edge]
+	if !ok {
+		return
+	}
+
+	delete(l.rules, edge)
+	l.decEdgeCount(edge.from)
+	l.decEdgeCount(edge.to)
+}
+
+func (l *EdgeLayer) GetEdgeGValue(fromCol int, fromRow int, toCol int, toRow int) (uint32, bool) {
+	if !l.CanCross(toCol, toRow) {
+		return 0, false
+	}
+
+	edge := gridEdge{from: Grid{Col: fromCol, Row: fromRow}, to: Grid{Col: toCol, Row: toRow}}
+	rule, ok := l.rules[edge]
+	if !ok {
+		return getEdgeGValue(l.NavigationMap, fromCol, fromRow, toCol, toRow)
+	}
+
+	return rule.gValue, rule.bCanCross
+}
+
+func (l *EdgeLayer) HasEdgeCost(col int, row int) bool {
+	return l.edgeCounts[Grid{Col: col, Row: row}] > 0 || hasEdgeCost(l.NavigationMap, col, row)
+}
+
+func (l *EdgeLayer) GetMinGValue() uint32 {
+	minGValue := l.NavigationMap.GetMinGValue()
+	if l.bHasMin && l.minEdgeGValue < minGValue {
+		return l.minEdgeGValue
+	}
+
+	return minGValue
+}
+
+func (l *EdgeLayer) decEdgeCount(grid Grid) {
+	l.edgeCounts[grid]--
+	if l.edgeCounts[grid] <= 0 {
+		delete(l.edgeCounts, grid)
+	}
+}
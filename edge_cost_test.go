@@ -0,0 +1,65 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+// a wall between col 1 and col 2 splits the map
+func newTestWallLayer(t *testing.T) *EdgeLayer {
+	l := NewEdgeLayer(newTestMap(t, ".....", ".....", "....."))
+	for row := 0; row < 3; row++ {
+		l.SetWall(NewGrid(1, row), NewGrid(2, row))
+	}
+
+	return l
+}
+
+func TestWrappedEdgeLayer(t *testing.T) {
+	profile := NewMovementProfile("walk")
+	profile.SetTerrain(0, true, 100)
+
+	cases := []struct {
+		name   string
+		finder PathFinder
+		wrap   func(l *EdgeLayer) NavigationMap
+	}{
+		{"astar link layer", NewAStar(), func(l *EdgeLayer) NavigationMap { return NewLinkLayer(l) }},
+		{"jps rect map", NewJps(0, false), func(l *EdgeLayer) NavigationMap { return NewRectMap(l, 0, 0, 4, 2) }},
+		{"astar agent size map", NewAStar(), func(l *EdgeLayer) NavigationMap {
+			return NewAgentSizeMap(l, NewClearanceMap(l), 1)
+		}},
+		{"astar profile map", NewAStar(), func(l *EdgeLayer) NavigationMap {
+			return NewProfileMap(NewTerrainLayer(l), profile)
+		}},
+		{"jps rect map of link layer", NewJps(0, false), func(l *EdgeLayer) NavigationMap {
+			return NewRectMap(NewLinkLayer(l), 0, 0, 4, 2)
+		}},
+		{"astar edge layer of edge layer", NewAStar(), func(l *EdgeLayer) NavigationMap { return NewEdgeLayer(l) }},
+	}
+
+	for _, c := range cases {
+		m := c.wrap(newTestWallLayer(t))
+		if _, ok := c.finder.FindPath(m, NewGrid(0, 1), NewGrid(4, 1)); ok {
+			t.Errorf("%s: path found through the wall", c.name)
+		}
+	}
+}
+
+func TestStackedLinkLayer(t *testing.T) {
+	links := NewLinkLayer(newTestWallLayer(t))
+	links.AddLink(NewGrid(0, 0), NewGrid(4, 0), 10, LinkTypeTeleport, false)
+	m := NewRectMap(links, 0, 0, 4, 2)
+
+	// (0, 1) -> (0, 0) -> link -> (4, 0) -> (4, 1)
+	gValue, ok := getPathGValue(t, NewAStar(), m, NewGrid(0, 1), NewGrid(4, 1))
+	if !ok || gValue != 12 {
+		t.Errorf("got g value %d (%v), want 12", gValue, ok)
+	}
+
+	// the link only goes one way
+	if _, ok := NewAStar().FindPath(m, NewGrid(4, 1), NewGrid(0, 1)); ok {
+		t.Errorf("path found back through the wall")
+	}
+}
@@ -0,0 +1,504 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+)
+
+// the binary encodings are magic(4) + version(1) + body + crc32(4), the crc32 is
+// computed on all the bytes before it. the json encodings carry the version and the
+// crc32 of the binary encoding of the same data, so both formats have the same checksum
+const (
+	encodingHeaderSize   = 5
+	encodingChecksumSize = 4
+	// the max count of the values or the grids decoded, the counts are read from
+	// the untrusted data, so they are checked before allocating
+	encodingMaxCount = 1 << 26
+)
+
+var (
+	ErrEncodingBadMagic    = errors.New("encoding: bad magic")
+	ErrEncodingBadVersion  = errors.New("encoding: unsupported version")
+	ErrEncodingBadSize     = errors.New("encoding: bad data size")
+	ErrEncodingBadChecksum = errors.New("encoding: bad checksum")
+	ErrEncodingMapMismatch = errors.New("encoding: the size of the map mismatch")
+)
+
+//========================
+//      binaryWriter
+//========================
+type binaryWriter struct {
+	buf []byte
+}
+
+func newBinaryWriter(magic string, version byte) *binaryWriter {
+	w := &binaryWriter{
+		buf: make([]byte, 0, 64),
+	}
+
+	w.buf = append(w.buf, magic...)
+	w.buf = append(w.buf, version)
+	return w
+}
+
+func (w *binaryWriter) putByte(v byte) {
+	w.buf = append(w.buf, v)
+}
+
+func (w *binaryWriter) putUvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	w.buf = append(w.buf, tmp[:n]...)
+}
+
+func (w *binaryWriter) putVarint(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	w.buf = append(w.buf, tmp[:n]...)
+}
+
+func (w *binaryWriter) putBytes(data []byte) {
+	w.buf = append(w.buf, data...)
+}
+
+// append the checksum and return the encoding
+func (w *binaryWriter) finish() []byte {
+	return appendChecksum(w.buf)
+}
+
+//========================
+//      binaryReader
+//========================
+// the first error is kept, the reads after it return 0
+type binaryReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+// check the magic, the version and the checksum, then read the body
+func newBinaryReader(data []byte, magic string, version byte) (*binaryReader, error) {
+	if len(data) < encodingHeaderSize+encodingChecksumSize {
+		return nil, ErrEncodingBadSize
+	}
+
+	if string(data[:4]) != magic {
+		return nil, ErrEncodingBadMagic
+	}
+
+	if data[4] != version {
+		return nil, ErrEncodingBadVersion
+	}
+
+	body, err := verifyChecksum(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return &binaryReader{data: body, pos: encodingHeaderSize}, nil
+}
+
+func (r *binaryReader) getByte() byte {
+	if r.err != nil || r.pos >= len(r.data) {
+		r.err = ErrEncodingBadSize
+		return 0
+	}
+
+	v := r.data[r.pos]
+	r.pos++
+	return v
+}
+
+func (r *binaryReader) getUvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(r.data[r.pos:])
+	if n <= 0 {
+		r.err = ErrEncodingBadSize
+		return 0
+	}
+
+	r.pos += n
+	return v
+}
+
+func (r *binaryReader) getVarint() int64 {
+	if r.err != nil {
+		return 0
+	}
+
+	v, n := binary.Varint(r.data[r.pos:])
+	if n <= 0 {
+		r.err = ErrEncodingBadSize
+		return 0
+	}
+
+	r.pos += n
+	return v
+}
+
+// a count can't be larger than the bytes left, which avoids a huge allocation by a bad data
+func (r *binaryReader) getCount(bytesPerItem int) int {
+	count := r.getUvarint()
+	if r.err == nil && count > uint64((len(r.data)-r.pos)/bytesPerItem) {
+		r.err = ErrEncodingBadSize
+		return 0
+	}
+
+	return int(count)
+}
+
+func (r *binaryReader) getBytes(n int) []byte {
+	if r.err != nil || n < 0 || r.pos+n > len(r.data) {
+		r.err = ErrEncodingBadSize
+		return nil
+	}
+
+	v := r.data[r.pos : r.pos+n]
+	r.pos += n
+	return v
+}
+
+// the error of the reads, or all the bytes should be read
+func (r *binaryReader) finish() error {
+	if r.err != nil {
+		return r.err
+	}
+
+	if r.pos != len(r.data) {
+		return ErrEncodingBadSize
+	}
+
+	return nil
+}
+
+func appendChecksum(data []byte) []byte {
+	var tmp [encodingChecksumSize]byte
+	binary.LittleEndian.PutUint32(tmp[:], crc32.ChecksumIEEE(data))
+	return append(data, tmp[:]...)
+}
+
+// return the data without the checksum
+func verifyChecksum(data []byte) ([]byte, error) {
+	if len(data) < encodingChecksumSize {
+		return nil, ErrEncodingBadSize
+	}
+
+	body := data[:len(data)-encodingChecksumSize]
+	if binary.LittleEndian.Uint32(data[len(body):]) != crc32.ChecksumIEEE(body) {
+		return nil, ErrEncodingBadChecksum
+	}
+
+	return body, nil
+}
+
+// the checksum of a binary encoding
+func getChecksum(data []byte) uint32 {
+	return binary.LittleEndian.Uint32(data[len(data)-encodingChecksumSize:])
+}
+
+// the json encodings start with '{'
+func isJsonEncoding(data []byte) bool {
+	data = bytes.TrimLeft(data, " \t\r\n")
+	return len(data) > 0 && data[0] == '{'
+}
+
+//========================
+//        GridPath
+//========================
+const (
+	gridPathMagic   = "NAVP"
+	gridPathVersion = 1
+)
+
+// the grids of a path, encoded as the start grid and the runs of the same moves,
+// so the adjacent grids of AStar and the jump points of Jps are both compact
+type GridPath []Grid
+
+func NewGridPath(fullPath []PathNode) GridPath {
+	p := make(GridPath, 0, len(fullPath))
+	for _, node := range fullPath {
+		p = append(p, *node.GetGrid())
+	}
+
+	return p
+}
+
+// repeat the move (DX, DY) the count of times
+type gridPathRun struct {
+	DX     int
+	DY     int
+	Repeat int
+}
+
+type gridPathJson struct {
+	Version  int      `json:"version"`
+	Count    int      `json:"count"`
+	Start    []int    `json:"start,omitempty"`
+	Runs     [][3]int `json:"runs,omitempty"`
+	Checksum uint32   `json:"checksum"`
+}
+
+func (p GridPath) MarshalBinary() ([]byte, error) {
+	w := newBinaryWriter(gridPathMagic, gridPathVersion)
+	w.putUvarint(uint64(len(p)))
+	if len(p) == 0 {
+		return w.finish(), nil
+	}
+
+	runs := p.getRuns()
+	w.putVarint(int64(p[0].Col))
+	w.putVarint(int64(p[0].Row))
+	w.putUvarint(uint64(len(runs)))
+	for _, run := range runs {
+		w.putVarint(int64(run.DX))
+		w.putVarint(int64(run.DY))
+		w.putUvarint(uint64(run.Repeat))
+	}
+
+	return w.finish(), nil
+}
+
+func (p *GridPath) UnmarshalBinary(data []byte) error {
+	r, err := newBinaryReader(data, gridPathMagic, gridPathVersion)
+	if err != nil {
+		return err
+	}
+
+	count := r.getUvarint()
+	if count == 0 {
+		*p = GridPath{}
+		return r.finish()
+	}
+
+	start := Grid{Col: int(r.getVarint()), Row: int(r.getVarint())}
+	runs := make([]gridPathRun, r.getCount(3))
+	for i := range runs {
+		runs[i].DX = int(r.getVarint())
+		runs[i].DY = int(r.getVarint())
+		runs[i].Repeat = int(r.getUvarint())
+	}
+
+	if err := r.finish(); err != nil {
+		return err
+	}
+
+	path, ok := newGridPathFromRuns(start, runs, count)
+	if !ok {
+		return ErrEncodingBadSize
+	}
+
+	*p = path
+	return nil
+}
+
+func (p GridPath) MarshalJSON() ([]byte, error) {
+	data, _ := p.MarshalBinary()
+	j := &gridPathJson{
+		Version:  gridPathVersion,
+		Count:    len(p),
+		Checksum: getChecksum(data),
+	}
+
+	if len(p) > 0 {
+		j.Start = []int{p[0].Col, p[0].Row}
+		for _, run := range p.getRuns() {
+			j.Runs = append(j.Runs, [3]int{run.DX, run.DY, run.Repeat})
+		}
+	}
+
+	return json.Marshal(j)
+}
+
+func (p *GridPath) UnmarshalJSON(data []byte) error {
+	j := &gridPathJson{}
+	err := json.Unmarshal(data, j)
+	if err != nil {
+		return err
+	}
+
+	if j.Version != gridPathVersion {
+		return ErrEncodingBadVersion
+	}
+
+	path := GridPath{}
+	if j.Count > 0 {
+		if len(j.Start) != 2 {
+			return ErrEncodingBadSize
+		}
+
+		runs := make([]gridPathRun, 0, len(j.Runs))
+		for _, run := range j.Runs {
+			runs = append(runs, gridPathRun{DX: run[0], DY: run[1], Repeat: run[2]})
+		}
+
+		var ok bool
+		path, ok = newGridPathFromRuns(Grid{Col: j.Start[0], Row: j.Start[1]}, runs, uint64(j.Count))
+		if !ok {
+			return ErrEncodingBadSize
+		}
+	}
+
+	binaryData, _ := path.MarshalBinary()
+	if getChecksum(binaryData) != j.Checksum {
+		return ErrEncodingBadChecksum
+	}
+
+	*p = path
+	return nil
+}
+
+func (p GridPath) getRuns() []gridPathRun {
+	runs := make([]gridPathRun, 0)
+	for i := 1; i < len(p); i++ {
+		dx := p[i].Col - p[i-1].Col
+		dy := p[i].Row - p[i-1].Row
+		last := len(runs) - 1
+		if last >= 0 && runs[last].DX == dx && runs[last].DY == dy {
+			runs[last].Repeat++
+			continue
+		}
+
+		runs = append(runs, gridPathRun{DX: dx, DY: dy, Repeat: 1})
+	}
+
+	return runs
+}
+
+// the count of grids should match the runs
+func newGridPathFromRuns(start Grid, runs []gridPathRun, count uint64) (GridPath, bool) {
+	if count > encodingMaxCount {
+		return nil, false
+	}
+
+	total := uint64(1)
+	for _, run := range runs {
+		if run.Repeat <= 0 {
+			return nil, false
+		}
+
+		total += uint64(run.Repeat)
+		if total > count {
+			return nil, false
+		}
+	}
+
+	if total != count {
+		return nil, false
+	}
+
+	p := GridPath{start}
+	for _, run := range runs {
+		for i := 0; i < run.Repeat; i++ {
+			last := p[len(p)-1]
+			p = append(p, Grid{Col: last.Col + run.DX, Row: last.Row + run.DY})
+		}
+	}
+
+	return p, true
+}
+
+// the runs of the same value, [value, count]
+func getValueRuns(values []uint32) [][2]uint32 {
+	runs := make([][2]uint32, 0)
+	for _, v := range values {
+		last := len(runs) - 1
+		if last >= 0 && runs[last][0] == v {
+			runs[last][1]++
+			continue
+		}
+
+		runs = append(runs, [2]uint32{v, 1})
+	}
+
+	return runs
+}
+
+// the count of values should match the runs
+func newValuesFromRuns(runs [][2]uint32, count int) ([]uint32, bool) {
+	total := uint64(0)
+	for _, run := range runs {
+		if run[1] == 0 {
+			return nil, false
+		}
+
+		total += uint64(run[1])
+	}
+
+	if count < 0 || count > encodingMaxCount || total != uint64(count) {
+		return nil, false
+	}
+
+	values := make([]uint32, 0, count)
+	for _, run := range runs {
+		for i := uint32(0); i < run[1]; i++ {
+			values = append(values, run[0])
+		}
+	}
+
+	return values, true
+}
+
+func (w *binaryWriter) putValueRuns(values []uint32) {
+	runs := getValueRuns(values)
+	w.putUvarint(uint64(len(runs)))
+	for _, run := range runs {
+		w.putUvarint(uint64(run[0]))
+		w.putUvarint(uint64(run[1]))
+	}
+}
+
+func (r *binaryReader) getValueRuns(count int) []uint32 {
+	runs := make([][2]uint32, r.getCount(2))
+	for i := range runs {
+		runs[i][0] = uint32(r.getUvarint())
+		runs[i][1] = uint32(r.getUvarint())
+	}
+
+	if r.err != nil {
+		return nil
+	}
+
+	values, ok := newValuesFromRuns(runs, count)
+	if !ok {
+		r.err = ErrEncodingBadSize
+		return nil
+	}
+
+	return values
+}
+
+// the precomputed data should be loaded for a map with the same size
+func checkMapSize(m NavigationMap, cols int, rows int) error {
+	if m == nil {
+		return nil
+	}
+
+	mapCols, mapRows := m.GetColRow()
+	if int(mapCols) != cols || int(mapRows) != rows {
+		return ErrEncodingMapMismatch
+	}
+
+	return nil
+}
+
+// unmarshal the binary or the json encoding
+func unmarshalAny(data []byte, v interface {
+	UnmarshalBinary(data []byte) error
+	UnmarshalJSON(data []byte) error
+}) error {
+	if isJsonEncoding(data) {
+		return v.UnmarshalJSON(data)
+	}
+
+	return v.UnmarshalBinary(data)
+}
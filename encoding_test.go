@@ -0,0 +1,43 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGridPathEncoding(t *testing.T) {
+	path := GridPath{{Col: 0, Row: 0}, {Col: 1, Row: 0}, {Col: 2, Row: 0}, {Col: 3, Row: 1}}
+	data, _ := path.MarshalBinary()
+	jsonData, _ := json.Marshal(path)
+	for _, encoded := range [][]byte{data, jsonData} {
+		loaded := GridPath{}
+		if err := unmarshalAny(encoded, &loaded); err != nil {
+			t.Fatal(err)
+		}
+
+		if len(loaded) != len(path) || !loaded[3].IsSameGrid(&path[3]) {
+			t.Errorf("got path %v, want %v", loaded, path)
+		}
+	}
+}
+
+// a few bytes claim a huge path, it should fail without allocating it
+func TestGridPathHugeCount(t *testing.T) {
+	w := newBinaryWriter(gridPathMagic, gridPathVersion)
+	w.putUvarint(1 << 36)
+	w.putVarint(0)
+	w.putVarint(0)
+	w.putUvarint(1)
+	w.putVarint(1)
+	w.putVarint(0)
+	w.putUvarint(1<<36 - 1)
+
+	path := GridPath{}
+	if err := path.UnmarshalBinary(w.finish()); err != ErrEncodingBadSize {
+		t.Errorf("got error %v, want %v", err, ErrEncodingBadSize)
+	}
+}
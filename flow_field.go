@@ -0,0 +1,254 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"container/heap"
+	"math"
+)
+
+//========================
+//       FlowField
+//========================
+// the costs to a shared dest grid and the next step of every grid, the agents
+// look up their moves instead of finding paths one by one
+type FlowField struct {
+	m            NavigationMap
+	connectivity Connectivity
+	cols         int
+	rows         int
+	dstGrid      *Grid
+	costs        []uint32
+	vectors      []*Vector
+}
+
+// panic with ErrUnboundedMap if the map is unbounded
+func NewFlowField(m NavigationMap, connectivity Connectivity) *FlowField {
+	checkBoundedMap(m)
+	return &FlowField{
+		m:            m,
+		connectivity: connectivity,
+	}
+}
+
+func (f *FlowField) GetConnectivity() Connectivity {
+	return f.connectivity
+}
+
+func (f *FlowField) GetDstGrid() *Grid {
+	return f.dstGrid
+}
+
+// one dijkstra pass from the dest grid over the whole map, return false if the dest grid can't be crossed
+func (f *FlowField) Build(dstGrid *Grid) bool {
+	cols, rows := f.m.GetColRow()
+	f.cols = int(cols)
+	f.rows = int(rows)
+	f.dstGrid = NewGrid(dstGrid.Col, dstGrid.Row)
+	f.costs = make([]uint32, f.cols*f.rows)
+	f.vectors = make([]*Vector, f.cols*f.rows)
+	for i := range f.costs {
+		f.costs[i] = math.MaxUint32
+	}
+
+	if !f.isInMap(dstGrid.Col, dstGrid.Row) || !f.m.CanCross(dstGrid.Col, dstGrid.Row) {
+		return false
+	}
+
+	f.costs[dstGrid.Row*f.cols+dstGrid.Col] = 0
+	open := &flowFieldHeap{}
+	heap.Push(open, flowFieldItem{idx: dstGrid.Row*f.cols + dstGrid.Col, cost: 0})
+	f.propagate(open)
+	return true
+}
+
+// the g value from the grid to the dest grid, return false if can't reach
+func (f *FlowField) GetCost(col int, row int) (uint32, bool) {
+	if !f.isInMap(col, row) {
+		return 0, false
+	}
+
+	cost := f.costs[row*f.cols+col]
+	return cost, cost != math.MaxUint32
+}
+
+// the move of the grid, nil for the dest grid or the grid can't reach
+func (f *FlowField) GetVector(col int, row int) *Vector {
+	if !f.isInMap(col, row) {
+		return nil
+	}
+
+	return f.vectors[row*f.cols+col]
+}
+
+func (f *FlowField) GetNextGrid(col int, row int) (*Grid, bool) {
+	vec := f.GetVector(col, row)
+	if vec == nil {
+		return nil, false
+	}
+
+	return NewGrid(col+vec.X, row+vec.Y), true
+}
+
+func (f *FlowField) UpdateGrid(col int, row int) {
+	f.UpdateRect(col, row, col, row)
+}
+
+// update the field after the grids in the rect changed, only the grids whose
+// moves depend on the rect are computed again
+func (f *FlowField) UpdateRect(left int, top int, right int, bottom int) {
+	if f.dstGrid == nil {
+		return
+	}
+
+	// the grids around may move obliquely by the changed grids
+	left = maxInt(left-1, 0)
+	top = maxInt(top-1, 0)
+	right = minInt(right+1, f.cols-1)
+	bottom = minInt(bottom+1, f.rows-1)
+	if left > right || top > bottom {
+		return
+	}
+
+	// the grids flow into the rect
+	affected := f.getAffectedGrids(left, top, right, bottom)
+	for _, idx := range affected {
+		f.costs[idx] = math.MaxUint32
+		f.vectors[idx] = nil
+	}
+
+	dstIdx := f.dstGrid.Row*f.cols + f.dstGrid.Col
+	open := &flowFieldHeap{}
+	if f.m.CanCross(f.dstGrid.Col, f.dstGrid.Row) {
+		f.costs[dstIdx] = 0
+		heap.Push(open, flowFieldItem{idx: dstIdx, cost: 0})
+	}
+
+	// compute the affected grids again from the grids not affected
+	for _, idx := range affected {
+		col := idx % f.cols
+		row := idx / f.cols
+		for _, vec := range getMoveVectors(f.connectivity) {
+			nextIdx := (row+vec.Y)*f.cols + col + vec.X
+			if !f.isInMap(col+vec.X, row+vec.Y) || f.costs[nextIdx] == math.MaxUint32 {
+				continue
+			}
+
+			heap.Push(open, flowFieldItem{idx: nextIdx, cost: f.costs[nextIdx]})
+		}
+	}
+
+	f.propagate(open)
+}
+
+// the grids in the rect and the grids whose moves lead into the rect, they are found
+// backwards from the rect, so the grids flow elsewhere are never visited
+func (f *FlowField) getAffectedGrids(left int, top int, right int, bottom int) []int {
+	bAffected := make(map[int]bool)
+	affected := make([]int, 0)
+	for row := top; row <= bottom; row++ {
+		for col := left; col <= right; col++ {
+			idx := row*f.cols + col
+			bAffected[idx] = true
+			affected = append(affected, idx)
+		}
+	}
+
+	for i := 0; i < len(affected); i++ {
+		col := affected[i] % f.cols
+		row := affected[i] / f.cols
+		for _, vec := range getMoveVectors(f.connectivity) {
+			fromCol := col - vec.X
+			fromRow := row - vec.Y
+			if !f.isInMap(fromCol, fromRow) {
+				continue
+			}
+
+			fromIdx := fromRow*f.cols + fromCol
+			fromVec := f.vectors[fromIdx]
+			if bAffected[fromIdx] || fromVec == nil || fromVec.X != vec.X || fromVec.Y != vec.Y {
+				continue
+			}
+
+			bAffected[fromIdx] = true
+			affected = append(affected, fromIdx)
+		}
+	}
+
+	return affected
+}
+
+// relax the grids move into the popped grid
+func (f *FlowField) propagate(open *flowFieldHeap) {
+	for open.Len() > 0 {
+		item := heap.Pop(open).(flowFieldItem)
+		if item.cost != f.costs[item.idx] {
+			continue
+		}
+
+		col := item.idx % f.cols
+		row := item.idx / f.cols
+		for _, vec := range getMoveVectors(f.connectivity) {
+			fromCol := col - vec.X
+			fromRow := row - vec.Y
+			if !f.isInMap(fromCol, fromRow) {
+				continue
+			}
+
+			addGValue, ok := getMoveGValue(f.m, f.connectivity, fromCol, fromRow, vec)
+			if !ok {
+				continue
+			}
+
+			fromIdx := fromRow*f.cols + fromCol
+			cost := uint64(item.cost) + uint64(addGValue)
+			if cost >= uint64(f.costs[fromIdx]) {
+				continue
+			}
+
+			f.costs[fromIdx] = uint32(cost)
+			f.vectors[fromIdx] = vec
+			heap.Push(open, flowFieldItem{idx: fromIdx, cost: uint32(cost)})
+		}
+	}
+}
+
+func (f *FlowField) isInMap(col int, row int) bool {
+	return col >= 0 && row >= 0 && col < f.cols && row < f.rows
+}
+
+//========================
+//     flowFieldHeap
+//========================
+type flowFieldItem struct {
+	idx  int
+	cost uint32
+}
+
+type flowFieldHeap []flowFieldItem
+
+func (h flowFieldHeap) Len() int {
+	return len(h)
+}
+
+func (h flowFieldHeap) Less(i int, j int) bool {
+	return h[i].cost < h[j].cost
+}
+
+func (h flowFieldHeap) Swap(i int, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *flowFieldHeap) Push(x interface{}) {
+	*h = append(*h, x.(flowFieldItem))
+}
+
+func (h *flowFieldHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,87 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// the costs are the same as a full build, and each move leads to the cost of the next grid
+func checkFlowField(t *testing.T, name string, f *FlowField, m NavigationMap) {
+	full := NewFlowField(m, f.GetConnectivity())
+	full.Build(f.GetDstGrid())
+	cols, rows := m.GetColRow()
+	for row := 0; row < int(rows); row++ {
+		for col := 0; col < int(cols); col++ {
+			cost, ok := f.GetCost(col, row)
+			want, bReachable := full.GetCost(col, row)
+			if ok != bReachable || cost != want {
+				t.Fatalf("%s: got cost %d (%v) at (%d, %d), want %d (%v)", name, cost, ok, col, row, want, bReachable)
+			}
+
+			vec := f.GetVector(col, row)
+			if vec == nil {
+				continue
+			}
+
+			nextCost, _ := f.GetCost(col+vec.X, row+vec.Y)
+			addGValue, ok := getMoveGValue(m, f.GetConnectivity(), col, row, vec)
+			if !ok || nextCost+addGValue != cost {
+				t.Fatalf("%s: the move %v at (%d, %d) doesn't lead to the cost %d", name, vec, col, row, cost)
+			}
+		}
+	}
+}
+
+func TestFlowFieldUpdateRect(t *testing.T) {
+	names := map[Connectivity]string{
+		Connectivity4:          "4",
+		Connectivity8:          "8",
+		Connectivity8CornerCut: "8 corner cut",
+	}
+
+	for connectivity, name := range names {
+		rnd := rand.New(rand.NewSource(int64(connectivity)))
+		m := newRandomTestMap(int64(connectivity), 20, 20, 0.2, 4)
+		m.SetCanCross(10, 10, true)
+		f := NewFlowField(m, connectivity)
+		f.Build(NewGrid(10, 10))
+		checkFlowField(t, name, f, m)
+
+		for i := 0; i < 50; i++ {
+			left := rnd.Intn(20)
+			top := rnd.Intn(20)
+			right := minInt(left+rnd.Intn(3), 19)
+			bottom := minInt(top+rnd.Intn(3), 19)
+			for row := top; row <= bottom; row++ {
+				for col := left; col <= right; col++ {
+					// open or close the walls, or change the g values
+					if rnd.Intn(2) == 0 {
+						m.SetCanCross(col, row, !m.CanCross(col, row))
+					} else {
+						m.SetGValue(col, row, uint32(1+rnd.Intn(4)))
+					}
+				}
+			}
+
+			f.UpdateRect(left, top, right, bottom)
+			checkFlowField(t, name, f, m)
+		}
+	}
+}
+
+// the grids flow elsewhere keep their moves
+func TestFlowFieldAffectedGrids(t *testing.T) {
+	m := newTestMap(t, "........")
+	f := NewFlowField(m, Connectivity4)
+	f.Build(NewGrid(0, 0))
+
+	// only (7, 0) moves through (6, 0)
+	affected := f.getAffectedGrids(6, 0, 6, 0)
+	if len(affected) != 2 {
+		t.Errorf("got %d affected grids, want 2", len(affected))
+	}
+}
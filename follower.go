@@ -0,0 +1,390 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "math"
+
+const (
+	// the distance in grids to snap to the end of the path
+	followerArriveEpsilon = 1e-3
+	// the margin in grids of the local search to repair a blocked path
+	followerRepairMargin = 8
+)
+
+//========================
+//  PathFollowerListener
+//========================
+type PathFollowerListener interface {
+	// the agent passes the grid of the path at the index
+	OnWaypointReached(index int, grid *Grid)
+	// the grid ahead can't be crossed, bRepathed is false if there is no finder or no new
+	// path, then the agent stops and the path is requested again at the next update
+	OnPathBlocked(grid *Grid, bRepathed bool)
+	OnArrived()
+}
+
+// the empty callbacks, embed it to implement only the needed ones
+type BasePathFollowerListener struct {
+}
+
+func (l *BasePathFollowerListener) OnWaypointReached(index int, grid *Grid) {
+}
+
+func (l *BasePathFollowerListener) OnPathBlocked(grid *Grid, bRepathed bool) {
+}
+
+func (l *BasePathFollowerListener) OnArrived() {
+}
+
+//========================
+//      PathFollower
+//========================
+// a grid of the path and the distance along the path to its center
+type followerCell struct {
+	grid Grid
+	dist float64
+}
+
+// move an agent along a path in the world space, the path can be dense (AStar) or
+// sparse (Jps), the agent steers to the point of the lookahead distance ahead on the
+// path, or a nearer one if the line to it is not clear
+type PathFollower struct {
+	m             NavigationMap
+	t             *GridTransform
+	finder        PathFinder
+	listener      PathFollowerListener
+	speed         float64
+	lookahead     float64
+	arrivalRadius float64
+
+	path      GridPath
+	points    []*WorldPos
+	pointDist []float64
+	cells     []*followerCell
+	cell      int
+	pos       *WorldPos
+	velocity  *WorldPos
+	target    *WorldPos
+	progress  float64
+	segment   int
+	waypoint  int
+	bArrived  bool
+}
+
+func NewPathFollower(m NavigationMap, t *GridTransform) *PathFollower {
+	f := &PathFollower{
+		m:        m,
+		t:        t,
+		speed:    t.GetCellSize(),
+		pos:      NewWorldPos(0, 0),
+		velocity: NewWorldPos(0, 0),
+		target:   NewWorldPos(0, 0),
+		bArrived: true,
+	}
+
+	return f
+}
+
+// the finder to request a new path when the path is blocked, nil to stop only
+func (f *PathFollower) SetFinder(finder PathFinder) {
+	f.finder = finder
+}
+
+func (f *PathFollower) SetListener(listener PathFollowerListener) {
+	f.listener = listener
+}
+
+// the world distance per second
+func (f *PathFollower) SetSpeed(speed float64) {
+	f.speed = speed
+}
+
+// the world distance ahead on the path to steer to, 0 to follow the path exactly,
+// a larger one smooths the corners, the target is pulled back to keep the line clear
+func (f *PathFollower) SetLookahead(lookahead float64) {
+	f.lookahead = lookahead
+}
+
+// slow down when the distance to the end is less than the radius
+func (f *PathFollower) SetArrivalRadius(arrivalRadius float64) {
+	f.arrivalRadius = arrivalRadius
+}
+
+// follow the path from the position, the nodes are copied, so the finder can reuse them
+func (f *PathFollower) SetPath(fullPath []PathNode, pos *WorldPos) {
+	f.setPath(NewGridPath(fullPath), pos)
+}
+
+func (f *PathFollower) GetPath() GridPath {
+	return f.path
+}
+
+func (f *PathFollower) GetPosition() *WorldPos {
+	return f.pos
+}
+
+// the velocity of the last update
+func (f *PathFollower) GetVelocity() *WorldPos {
+	return f.velocity
+}
+
+// the steering target of the last update
+func (f *PathFollower) GetTarget() *WorldPos {
+	return f.target
+}
+
+// the index of the next grid of the path to reach
+func (f *PathFollower) GetWaypointIndex() int {
+	return f.waypoint
+}
+
+func (f *PathFollower) IsArrived() bool {
+	return f.bArrived
+}
+
+// the distance along the path to the end
+func (f *PathFollower) GetRemainingDistance() float64 {
+	if len(f.pointDist) == 0 {
+		return 0
+	}
+
+	return f.pointDist[len(f.pointDist)-1] - f.progress
+}
+
+// move the agent for the time, return the new position
+func (f *PathFollower) Update(dt float64) *WorldPos {
+	f.velocity = NewWorldPos(0, 0)
+	if f.bArrived || dt <= 0 {
+		return f.pos
+	}
+
+	step := f.getSpeed() * dt
+	if !f.checkBlocked(step) {
+		return f.pos
+	}
+
+	f.target = f.getSteerTarget(step)
+	dist := f.pos.GetDistance(f.target)
+	if dist > 0 {
+		move := math.Min(step, dist)
+		dirX := (f.target.X - f.pos.X) / dist
+		dirY := (f.target.Y - f.pos.Y) / dist
+		f.velocity = NewWorldPos(dirX*move/dt, dirY*move/dt)
+		f.pos = NewWorldPos(f.pos.X+dirX*move, f.pos.Y+dirY*move)
+	}
+
+	f.updateProgress(step)
+	f.checkArrived()
+	return f.pos
+}
+
+func (f *PathFollower) setPath(path GridPath, pos *WorldPos) {
+	f.path = path
+	f.pos = NewWorldPos(pos.X, pos.Y)
+	f.target = NewWorldPos(pos.X, pos.Y)
+	f.progress = 0
+	f.segment = 0
+	f.waypoint = 1
+	f.bArrived = false
+
+	// the agent goes to the second grid directly, not back to the center of the start grid
+	f.points = []*WorldPos{f.pos}
+	f.pointDist = []float64{0}
+	f.cells = f.cells[:0]
+	f.cell = 0
+	for i := 1; i < len(path); i++ {
+		point := f.t.GridToWorld(&path[i])
+		last := len(f.points) - 1
+		dist := f.pointDist[last] + f.points[last].GetDistance(point)
+		f.addCells(&path[i-1], &path[i], f.pointDist[last], dist)
+		f.points = append(f.points, point)
+		f.pointDist = append(f.pointDist, dist)
+	}
+
+	f.checkArrived()
+}
+
+// the grids from the one (excluded) to the other (included), along the straight
+// line of a jump, or to the next grid of a dense path
+func (f *PathFollower) addCells(from *Grid, to *Grid, fromDist float64, toDist float64) {
+	steps := maxInt(absInt(to.Col-from.Col), absInt(to.Row-from.Row))
+	grid := *from
+	for i := 1; i <= steps; i++ {
+		grid.Col += signInt(to.Col - grid.Col)
+		grid.Row += signInt(to.Row - grid.Row)
+		f.cells = append(f.cells, &followerCell{
+			grid: grid,
+			dist: fromDist + (toDist-fromDist)*float64(i)/float64(steps),
+		})
+	}
+}
+
+// the speed slows down in the arrival radius
+func (f *PathFollower) getSpeed() float64 {
+	remaining := f.GetRemainingDistance()
+	if f.arrivalRadius <= 0 || remaining >= f.arrivalRadius {
+		return f.speed
+	}
+
+	// not too slow to arrive
+	minSpeed := f.speed * 0.1
+	return math.Max(f.speed*remaining/f.arrivalRadius, minSpeed)
+}
+
+// the point of the lookahead distance ahead, pull it back along the path until the line
+// to it is clear, so the agent doesn't cut the walls at the corners
+func (f *PathFollower) getSteerTarget(step float64) *WorldPos {
+	stride := f.t.GetCellSize() * 0.5
+	for dist := math.Max(f.lookahead, step); dist > step; dist -= stride {
+		target := f.getPointAt(f.progress + dist)
+		if hasLineOfSight(f.m, f.t, f.pos, target) {
+			return target
+		}
+	}
+
+	// near enough to go along the path
+	return f.getPointAt(f.progress + step)
+}
+
+// the point at the distance along the path
+func (f *PathFollower) getPointAt(dist float64) *WorldPos {
+	last := len(f.points) - 1
+	if dist >= f.pointDist[last] {
+		return f.points[last]
+	}
+
+	for i := f.segment; i < last; i++ {
+		if dist > f.pointDist[i+1] {
+			continue
+		}
+
+		length := f.pointDist[i+1] - f.pointDist[i]
+		if length <= 0 {
+			return f.points[i+1]
+		}
+
+		ratio := (dist - f.pointDist[i]) / length
+		from := f.points[i]
+		to := f.points[i+1]
+		return NewWorldPos(from.X+(to.X-from.X)*ratio, from.Y+(to.Y-from.Y)*ratio)
+	}
+
+	return f.points[last]
+}
+
+// project the position to the path ahead, the progress never goes back
+func (f *PathFollower) updateProgress(step float64) {
+	maxDist := f.progress + math.Max(f.lookahead, step) + f.t.GetCellSize()
+	bestDistSq := math.MaxFloat64
+	bestSegment := f.segment
+	bestProgress := f.progress
+	for i := f.segment; i < len(f.points)-1 && f.pointDist[i] <= maxDist; i++ {
+		from := f.points[i]
+		to := f.points[i+1]
+		dx := to.X - from.X
+		dy := to.Y - from.Y
+		lengthSq := dx*dx + dy*dy
+		ratio := 0.0
+		if lengthSq > 0 {
+			ratio = ((f.pos.X-from.X)*dx + (f.pos.Y-from.Y)*dy) / lengthSq
+			ratio = math.Max(0, math.Min(1, ratio))
+		}
+
+		px := from.X + dx*ratio - f.pos.X
+		py := from.Y + dy*ratio - f.pos.Y
+		distSq := px*px + py*py
+		progress := f.pointDist[i] + (f.pointDist[i+1]-f.pointDist[i])*ratio
+		if distSq < bestDistSq && progress >= f.progress {
+			bestDistSq = distSq
+			bestSegment = i
+			bestProgress = progress
+		}
+	}
+
+	f.segment = bestSegment
+	f.progress = bestProgress
+
+	epsilon := followerArriveEpsilon * f.t.GetCellSize()
+	for f.waypoint < len(f.points) && f.pointDist[f.waypoint] <= f.progress+epsilon {
+		if f.listener != nil {
+			f.listener.OnWaypointReached(f.waypoint, &f.path[f.waypoint])
+		}
+
+		f.waypoint++
+	}
+}
+
+func (f *PathFollower) checkArrived() {
+	last := len(f.points) - 1
+	epsilon := followerArriveEpsilon * f.t.GetCellSize()
+	if last > 0 && f.pos.GetDistance(f.points[last]) > epsilon {
+		return
+	}
+
+	if last > 0 {
+		f.pos = NewWorldPos(f.points[last].X, f.points[last].Y)
+		f.progress = f.pointDist[last]
+	}
+
+	f.bArrived = true
+	if f.listener != nil {
+		f.listener.OnArrived()
+	}
+}
+
+// check the grids ahead, request a new path if one can't be crossed, return
+// false if the agent can't go on
+func (f *PathFollower) checkBlocked(step float64) bool {
+	for f.cell < len(f.cells) && f.cells[f.cell].dist <= f.progress {
+		f.cell++
+	}
+
+	maxDist := f.progress + math.Max(f.lookahead, step) + f.t.GetCellSize()
+	for _, cell := range f.cells[f.cell:] {
+		if cell.dist > maxDist {
+			break
+		}
+
+		if f.m.CanCross(cell.grid.Col, cell.grid.Row) {
+			continue
+		}
+
+		bRepathed := f.repath()
+		if f.listener != nil {
+			f.listener.OnPathBlocked(&cell.grid, bRepathed)
+		}
+
+		return bRepathed
+	}
+
+	return true
+}
+
+// repair the rest of the path from the current position
+func (f *PathFollower) repath() bool {
+	if f.finder == nil || len(f.path) == 0 {
+		return false
+	}
+
+	startGrid, ok := SnapToCrossable(f.m, f.t, f.pos, worldSnapRadius)
+	if !ok {
+		return false
+	}
+
+	rest := GridPath{*startGrid}
+	for _, grid := range f.path[f.waypoint:] {
+		if !grid.IsSameGrid(&rest[len(rest)-1]) {
+			rest = append(rest, grid)
+		}
+	}
+
+	path, ok := RepairPath(f.finder, f.m, rest, followerRepairMargin)
+	if !ok {
+		return false
+	}
+
+	f.setPath(path, f.pos)
+	return true
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+func TestPathFollowerLookahead(t *testing.T) {
+	m := newTestMap(t, "......", "####..", "####..", "......", "..####", "......")
+	transform := NewGridTransform(0, 0, 1)
+	finders := map[string]PathFinder{
+		"astar": NewAStar(),
+		"jps":   NewJps(0, false),
+	}
+
+	for name, finder := range finders {
+		startGrid := NewGrid(0, 0)
+		fullPath, ok := finder.FindPath(m, startGrid, NewGrid(5, 5))
+		if !ok {
+			t.Fatalf("%s: no path", name)
+		}
+
+		f := NewPathFollower(m, transform)
+		f.SetLookahead(4)
+		f.SetPath(fullPath, transform.GridToWorld(startGrid))
+		for i := 0; i < 1000 && !f.IsArrived(); i++ {
+			pos := f.Update(0.1)
+			grid := transform.WorldToGrid(pos)
+			if !m.CanCross(grid.Col, grid.Row) {
+				t.Fatalf("%s: the agent is in the grid can't be crossed %v at %v", name, grid, pos)
+			}
+		}
+
+		if !f.IsArrived() {
+			t.Errorf("%s: the agent doesn't arrive", name)
+		}
+	}
+}
+
+func TestLineOfSight(t *testing.T) {
+	m := newTestMap(t, "...", ".#.", "...")
+	transform := NewGridTransform(0, 0, 1)
+	cases := []struct {
+		from *WorldPos
+		to   *WorldPos
+		want bool
+	}{
+		{NewWorldPos(0.5, 0.5), NewWorldPos(2.5, 0.5), true},
+		{NewWorldPos(0.5, 0.5), NewWorldPos(2.5, 2.5), false},
+		{NewWorldPos(0.5, 1.5), NewWorldPos(2.5, 1.5), false},
+		{NewWorldPos(0.5, 0.5), NewWorldPos(0.5, 2.5), true},
+		{NewWorldPos(0.5, 0.5), NewWorldPos(1.5, 0.5), true},
+		// through the corner of the blocked grid
+		{NewWorldPos(0.5, 1.5), NewWorldPos(1.5, 0.5), false},
+		{NewWorldPos(2.5, 0.5), NewWorldPos(2.5, 0.5), true},
+	}
+
+	for _, c := range cases {
+		if got := hasLineOfSight(m, transform, c.from, c.to); got != c.want {
+			t.Errorf("line of sight %v -> %v: got %v, want %v", c.from, c.to, got, c.want)
+		}
+	}
+}
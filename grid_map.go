@@ -0,0 +1,280 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"encoding/json"
+	"math"
+)
+
+//========================
+//       GridMap
+//========================
+// a simple navigation map keeps the crossable state and the g value of each grid
+type GridMap struct {
+	cols      int
+	rows      int
+	bCanCross []bool
+	gValues   []uint32
+	minGValue uint32
+	bMinDirty bool
+	version   uint64
+}
+
+// all the grids can be crossed with the g value
+func NewGridMap(cols uint32, rows uint32, gValue uint32) *GridMap {
+	m := &GridMap{
+		cols:      int(cols),
+		rows:      int(rows),
+		bCanCross: make([]bool, int(cols)*int(rows)),
+		gValues:   make([]uint32, int(cols)*int(rows)),
+		minGValue: gValue,
+	}
+
+	for i := range m.bCanCross {
+		m.bCanCross[i] = true
+		m.gValues[i] = gValue
+	}
+
+	return m
+}
+
+// copy the crossable states and the g values of a map, the copy can be
+// shared by the finders in different goroutines if no one changes it, panic with
+// ErrUnboundedMap if the map is unbounded
+func NewGridMapFrom(src NavigationMap) *GridMap {
+	checkBoundedMap(src)
+	cols, rows := src.GetColRow()
+	m := NewGridMap(cols, rows, 0)
+	for row := 0; row < m.rows; row++ {
+		for col := 0; col < m.cols; col++ {
+			idx := row*m.cols + col
+			m.bCanCross[idx] = src.CanCross(col, row)
+			m.gValues[idx] = src.GetGValue(col, row)
+		}
+	}
+
+	m.updateMinGValue()
+	return m
+}
+
+func (m *GridMap) SetCanCross(col int, row int, bCanCross bool) {
+	if !m.isInMap(col, row) {
+		return
+	}
+
+	m.bCanCross[row*m.cols+col] = bCanCross
+	m.bMinDirty = true
+	m.version++
+}
+
+func (m *GridMap) SetGValue(col int, row int, gValue uint32) {
+	if !m.isInMap(col, row) {
+		return
+	}
+
+	m.gValues[row*m.cols+col] = gValue
+	m.bMinDirty = true
+	m.version++
+}
+
+// increase after any grid changed
+func (m *GridMap) GetVersion() uint64 {
+	return m.version
+}
+
+func (m *GridMap) GetColRow() (col uint32, row uint32) {
+	return uint32(m.cols), uint32(m.rows)
+}
+
+func (m *GridMap) CanCross(col int, row int) bool {
+	if !m.isInMap(col, row) {
+		return false
+	}
+
+	return m.bCanCross[row*m.cols+col]
+}
+
+func (m *GridMap) GetGValue(col int, row int) uint32 {
+	if !m.isInMap(col, row) {
+		return 0
+	}
+
+	return m.gValues[row*m.cols+col]
+}
+
+// the min g value of the crossable grids
+func (m *GridMap) GetMinGValue() uint32 {
+	if m.bMinDirty {
+		m.updateMinGValue()
+	}
+
+	return m.minGValue
+}
+
+func (m *GridMap) updateMinGValue() {
+	minGValue := uint32(math.MaxUint32)
+	for i, bCanCross := range m.bCanCross {
+		if bCanCross && m.gValues[i] < minGValue {
+			minGValue = m.gValues[i]
+		}
+	}
+
+	if minGValue == math.MaxUint32 {
+		minGValue = 0
+	}
+
+	m.minGValue = minGValue
+	m.bMinDirty = false
+}
+
+// replace all the grids, the map becomes a new version
+func (m *GridMap) setGrids(cols int, rows int, gValues []uint32, canCross func(i int) bool) {
+	m.cols = cols
+	m.rows = rows
+	m.bCanCross = make([]bool, cols*rows)
+	for i := range m.bCanCross {
+		m.bCanCross[i] = canCross(i)
+	}
+
+	m.gValues = gValues
+	m.bMinDirty = true
+	m.version++
+}
+
+func (m *GridMap) isInMap(col int, row int) bool {
+	return col >= 0 && row >= 0 && col < m.cols && row < m.rows
+}
+
+//========================
+//     GridMap Encoding
+//========================
+const (
+	gridMapMagic   = "NAVM"
+	gridMapVersion = 1
+)
+
+// '#' can't be crossed and '.' can be crossed, the g values are the runs of [g value, count]
+type gridMapJson struct {
+	Version  int         `json:"version"`
+	Cols     int         `json:"cols"`
+	Rows     int         `json:"rows"`
+	Grids    []string    `json:"grids"`
+	GValues  [][2]uint32 `json:"gValues"`
+	Checksum uint32      `json:"checksum"`
+}
+
+// a bit for the crossable state of each grid, and the runs of the g values
+func (m *GridMap) MarshalBinary() ([]byte, error) {
+	w := newBinaryWriter(gridMapMagic, gridMapVersion)
+	w.putUvarint(uint64(m.cols))
+	w.putUvarint(uint64(m.rows))
+
+	bits := make([]byte, (len(m.bCanCross)+7)/8)
+	for i, bCanCross := range m.bCanCross {
+		if bCanCross {
+			bits[i/8] |= 1 << uint(i%8)
+		}
+	}
+
+	w.putBytes(bits)
+	w.putValueRuns(m.gValues)
+	return w.finish(), nil
+}
+
+// the version of the map increases, so the caches of the former map are dropped
+func (m *GridMap) UnmarshalBinary(data []byte) error {
+	r, err := newBinaryReader(data, gridMapMagic, gridMapVersion)
+	if err != nil {
+		return err
+	}
+
+	cols := r.getUvarint()
+	rows := r.getUvarint()
+	if r.err == nil && (rows != 0 && cols > uint64(len(data))*8/rows) {
+		return ErrEncodingBadSize
+	}
+
+	count := int(cols * rows)
+	bits := r.getBytes((count + 7) / 8)
+	gValues := r.getValueRuns(count)
+	if err := r.finish(); err != nil {
+		return err
+	}
+
+	m.setGrids(int(cols), int(rows), gValues, func(i int) bool {
+		return bits[i/8]&(1<<uint(i%8)) != 0
+	})
+
+	return nil
+}
+
+func (m *GridMap) MarshalJSON() ([]byte, error) {
+	data, _ := m.MarshalBinary()
+	j := &gridMapJson{
+		Version:  gridMapVersion,
+		Cols:     m.cols,
+		Rows:     m.rows,
+		Grids:    make([]string, 0, m.rows),
+		GValues:  getValueRuns(m.gValues),
+		Checksum: getChecksum(data),
+	}
+
+	for row := 0; row < m.rows; row++ {
+		line := make([]byte, m.cols)
+		for col := 0; col < m.cols; col++ {
+			line[col] = '#'
+			if m.bCanCross[row*m.cols+col] {
+				line[col] = '.'
+			}
+		}
+
+		j.Grids = append(j.Grids, string(line))
+	}
+
+	return json.Marshal(j)
+}
+
+func (m *GridMap) UnmarshalJSON(data []byte) error {
+	j := &gridMapJson{}
+	err := json.Unmarshal(data, j)
+	if err != nil {
+		return err
+	}
+
+	if j.Version != gridMapVersion {
+		return ErrEncodingBadVersion
+	}
+
+	if j.Cols < 0 || j.Rows != len(j.Grids) {
+		return ErrEncodingBadSize
+	}
+
+	for _, line := range j.Grids {
+		if len(line) != j.Cols {
+			return ErrEncodingBadSize
+		}
+	}
+
+	gValues, ok := newValuesFromRuns(j.GValues, j.Cols*j.Rows)
+	if !ok {
+		return ErrEncodingBadSize
+	}
+
+	decoded := &GridMap{}
+	decoded.setGrids(j.Cols, j.Rows, gValues, func(i int) bool {
+		return j.Grids[i/j.Cols][i%j.Cols] != '#'
+	})
+
+	binaryData, _ := decoded.MarshalBinary()
+	if getChecksum(binaryData) != j.Checksum {
+		return ErrEncodingBadChecksum
+	}
+
+	m.setGrids(decoded.cols, decoded.rows, decoded.gValues, func(i int) bool {
+		return decoded.bCanCross[i]
+	})
+	return nil
+}
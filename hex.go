@@ -0,0 +1,146 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+//========================
+//      HexLayout
+//========================
+type HexOrientation int
+
+const (
+	// the rows are shifted by half a hex
+	HexPointy HexOrientation = iota
+	// the columns are shifted by half a hex
+	HexFlat
+)
+
+type HexOffset int
+
+const (
+	// the odd rows (pointy) or the odd columns (flat) are shoved by half a hex
+	HexOffsetOdd HexOffset = iota
+	// the even rows (pointy) or the even columns (flat) are shoved by half a hex
+	HexOffsetEven
+)
+
+// the axial coordinate of a hex, the third cube coordinate is -Q-R
+type HexAxial struct {
+	Q int
+	R int
+}
+
+// the 6 neighbours in axial coordinates
+var hexAxialDirections = []HexAxial{
+	{Q: 1, R: 0},
+	{Q: 1, R: -1},
+	{Q: 0, R: -1},
+	{Q: -1, R: 0},
+	{Q: -1, R: 1},
+	{Q: 0, R: 1},
+}
+
+// how the offset coordinates (col, row) of a navigation map are laid out as hexes
+type HexLayout struct {
+	orientation HexOrientation
+	offset      HexOffset
+}
+
+func NewHexLayout(orientation HexOrientation, offset HexOffset) *HexLayout {
+	return &HexLayout{
+		orientation: orientation,
+		offset:      offset,
+	}
+}
+
+func (l *HexLayout) GetOrientation() HexOrientation {
+	return l.orientation
+}
+
+func (l *HexLayout) GetOffset() HexOffset {
+	return l.offset
+}
+
+func (l *HexLayout) OffsetToAxial(col int, row int) HexAxial {
+	if l.orientation == HexPointy {
+		return HexAxial{Q: col - l.getShift(row), R: row}
+	}
+
+	return HexAxial{Q: col, R: row - l.getShift(col)}
+}
+
+func (l *HexLayout) AxialToOffset(axial HexAxial) (col int, row int) {
+	if l.orientation == HexPointy {
+		return axial.Q + l.getShift(axial.R), axial.R
+	}
+
+	return axial.Q, axial.R + l.getShift(axial.Q)
+}
+
+// the 6 neighbours in offset coordinates, may be out of the map
+func (l *HexLayout) GetNeighbours(col int, row int) []*Grid {
+	axial := l.OffsetToAxial(col, row)
+	neighbours := make([]*Grid, 0, len(hexAxialDirections))
+	for _, dir := range hexAxialDirections {
+		nextCol, nextRow := l.AxialToOffset(HexAxial{Q: axial.Q + dir.Q, R: axial.R + dir.R})
+		neighbours = append(neighbours, NewGrid(nextCol, nextRow))
+	}
+
+	return neighbours
+}
+
+// the least moves between two hexes
+func (l *HexLayout) GetDistance(grid *Grid, dstGrid *Grid) uint32 {
+	from := l.OffsetToAxial(grid.Col, grid.Row)
+	to := l.OffsetToAxial(dstGrid.Col, dstGrid.Row)
+	dq := to.Q - from.Q
+	dr := to.R - from.R
+	return uint32((absInt(dq) + absInt(dr) + absInt(dq+dr)) / 2)
+}
+
+// a HeuristicFunc of hex distance
+func (l *HexLayout) Heuristic(grid *Grid, dstGrid *Grid, baseGValue uint32) uint32 {
+	return l.GetDistance(grid, dstGrid) * baseGValue
+}
+
+// the shift of the axial q (pointy) or r (flat) on the shoved line
+func (l *HexLayout) getShift(line int) int {
+	if l.offset == HexOffsetOdd {
+		return (line - (line & 1)) / 2
+	}
+
+	return (line + (line & 1)) / 2
+}
+
+//========================
+//        HexMap
+//========================
+// a view of a navigation map as hexes, AStar moves to the 6 neighbours with the hex distance heuristic.
+// the edge costs and the links of the map are kept
+type HexMap struct {
+	NavigationMap
+	mapForwarder
+	layout *HexLayout
+}
+
+func NewHexMap(m NavigationMap, layout *HexLayout) *HexMap {
+	return &HexMap{
+		NavigationMap: m,
+		mapForwarder:  mapForwarder{inner: m},
+		layout:        layout,
+	}
+}
+
+func (m *HexMap) GetLayout() *HexLayout {
+	return m.layout
+}
+
+func (m *HexMap) GetNeighbours(col int, row int) []*Grid {
+	return m.layout.GetNeighbours(col, row)
+}
+
+// the heuristic of the finder is for square grids, the map is asked with the hex distance instead
+func (m *HexMap) GetHValue(grid *Grid, dstGrid *Grid, baseGValue uint32, heuristic HeuristicFunc) uint32 {
+	return getHValue(m.inner, grid, dstGrid, baseGValue, m.layout.Heuristic)
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+var testHexLayouts = map[string]*HexLayout{
+	"pointy odd":  NewHexLayout(HexPointy, HexOffsetOdd),
+	"pointy even": NewHexLayout(HexPointy, HexOffsetEven),
+	"flat odd":    NewHexLayout(HexFlat, HexOffsetOdd),
+	"flat even":   NewHexLayout(HexFlat, HexOffsetEven),
+}
+
+func TestHexLayoutCoordinates(t *testing.T) {
+	for name, layout := range testHexLayouts {
+		for row := -5; row <= 5; row++ {
+			for col := -5; col <= 5; col++ {
+				if c, r := layout.AxialToOffset(layout.OffsetToAxial(col, row)); c != col || r != row {
+					t.Errorf("%s: (%d, %d) is converted back to (%d, %d)", name, col, row, c, r)
+				}
+			}
+		}
+	}
+}
+
+func TestHexLayoutNeighbours(t *testing.T) {
+	// the odd rows are shoved right
+	want := map[Grid]bool{{Col: 2, Row: 1}: true, {Col: 2, Row: 0}: true, {Col: 1, Row: 0}: true,
+		{Col: 0, Row: 1}: true, {Col: 1, Row: 2}: true, {Col: 2, Row: 2}: true}
+	for _, grid := range testHexLayouts["pointy odd"].GetNeighbours(1, 1) {
+		if !want[*grid] {
+			t.Errorf("pointy odd: unexpected neighbour %v of (1, 1)", grid)
+		}
+	}
+
+	for name, layout := range testHexLayouts {
+		for row := -3; row <= 3; row++ {
+			for col := -3; col <= 3; col++ {
+				grid := NewGrid(col, row)
+				neighbours := make(map[Grid]bool)
+				for _, next := range layout.GetNeighbours(col, row) {
+					neighbours[*next] = true
+					if layout.GetDistance(grid, next) != 1 {
+						t.Errorf("%s: the neighbour %v of %v is not next to it", name, next, grid)
+					}
+
+					bBack := false
+					for _, back := range layout.GetNeighbours(next.Col, next.Row) {
+						bBack = bBack || back.IsSameGrid(grid)
+					}
+
+					if !bBack {
+						t.Errorf("%s: %v is not the neighbour of its neighbour %v", name, grid, next)
+					}
+				}
+
+				if len(neighbours) != 6 {
+					t.Errorf("%s: got %d neighbours of %v, want 6", name, len(neighbours), grid)
+				}
+			}
+		}
+	}
+}
+
+// the distance is the steps of the breadth first search
+func TestHexLayoutDistance(t *testing.T) {
+	for name, layout := range testHexLayouts {
+		start := NewGrid(0, 0)
+		steps := map[Grid]uint32{*start: 0}
+		queue := []*Grid{start}
+		for len(queue) > 0 {
+			grid := queue[0]
+			queue = queue[1:]
+			for _, next := range layout.GetNeighbours(grid.Col, grid.Row) {
+				if _, ok := steps[*next]; ok || absInt(next.Col) > 6 || absInt(next.Row) > 6 {
+					continue
+				}
+
+				steps[*next] = steps[*grid] + 1
+				queue = append(queue, next)
+			}
+		}
+
+		// the corners of the area may be reached around its border
+		for grid, step := range steps {
+			grid := grid
+			if absInt(grid.Col) <= 3 && absInt(grid.Row) <= 3 && layout.GetDistance(start, &grid) != step {
+				t.Errorf("%s: got distance %d to %v, want %d", name, layout.GetDistance(start, &grid), grid, step)
+			}
+		}
+	}
+}
+
+func TestHexMapForward(t *testing.T) {
+	layout := testHexLayouts["pointy odd"]
+	edges := NewEdgeLayer(newTestMap(t, "..."))
+	edges.SetEdge(NewGrid(0, 0), NewGrid(1, 0), 20, true)
+	if gValue, ok := getPathGValue(t, NewAStar(), NewHexMap(edges, layout), NewGrid(0, 0), NewGrid(2, 0)); !ok || gValue != 21 {
+		t.Errorf("edge: got g value %d (%v), want 21", gValue, ok)
+	}
+
+	links := NewLinkLayer(newTestMap(t, "..#.."))
+	links.AddLink(NewGrid(1, 0), NewGrid(3, 0), 1, LinkTypeTeleport, false)
+	m := NewHexMap(links, layout)
+	if gValue, ok := getPathGValue(t, NewAStar(), m, NewGrid(0, 0), NewGrid(4, 0)); !ok || gValue != 3 {
+		t.Errorf("link: got g value %d (%v), want 3", gValue, ok)
+	}
+
+	// the link is shorter than the hex distance
+	if hValue := m.GetHValue(NewGrid(0, 0), NewGrid(4, 0), 1, ManhattanHeuristic); hValue > 3 {
+		t.Errorf("got h value %d, want at most 3", hValue)
+	}
+}
@@ -0,0 +1,773 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"container/heap"
+	"math"
+)
+
+// an entrance shorter than this has only one transition in the middle,
+// otherwise it has two transitions at both ends
+const HpaMaxSingleTransitionLen = 6
+
+//========================
+//      HpaEdge
+//========================
+type HpaEdge struct {
+	Grid   Grid
+	GValue uint32
+}
+
+//========================
+//      HpaNode
+//========================
+type HpaNode struct {
+	grid       *Grid
+	intraEdges []*HpaEdge
+	interEdges []*HpaEdge
+}
+
+func NewHpaNode(col int, row int) *HpaNode {
+	return &HpaNode{
+		grid:       NewGrid(col, row),
+		intraEdges: make([]*HpaEdge, 0),
+		interEdges: make([]*HpaEdge, 0),
+	}
+}
+
+func (n *HpaNode) GetGrid() *Grid {
+	return n.grid
+}
+
+func (n *HpaNode) GetIntraEdges() []*HpaEdge {
+	return n.intraEdges
+}
+
+func (n *HpaNode) GetInterEdges() []*HpaEdge {
+	return n.interEdges
+}
+
+//========================
+//      HpaCluster
+//========================
+type HpaCluster struct {
+	left   int
+	top    int
+	right  int
+	bottom int
+	nodes  map[Grid]*HpaNode
+}
+
+func NewHpaCluster(left int, top int, right int, bottom int) *HpaCluster {
+	return &HpaCluster{
+		left:   left,
+		top:    top,
+		right:  right,
+		bottom: bottom,
+		nodes:  make(map[Grid]*HpaNode),
+	}
+}
+
+func (c *HpaCluster) Contains(col int, row int) bool {
+	return col >= c.left && col < c.right && row >= c.top && row < c.bottom
+}
+
+func (c *HpaCluster) GetNode(col int, row int) (*HpaNode, bool) {
+	node, ok := c.nodes[Grid{Col: col, Row: row}]
+	return node, ok
+}
+
+func (c *HpaCluster) GetNodes() map[Grid]*HpaNode {
+	return c.nodes
+}
+
+func (c *HpaCluster) addNode(col int, row int) *HpaNode {
+	grid := Grid{Col: col, Row: row}
+	node, ok := c.nodes[grid]
+	if !ok {
+		node = NewHpaNode(col, row)
+		c.nodes[grid] = node
+	}
+
+	return node
+}
+
+//========================
+//    hpaClusterMap
+//========================
+// limit the search inside a cluster
+type hpaClusterMap struct {
+	NavigationMap
+	mapForwarder
+	cluster *HpaCluster
+}
+
+func newHpaClusterMap(m NavigationMap, cluster *HpaCluster) *hpaClusterMap {
+	return &hpaClusterMap{
+		NavigationMap: m,
+		mapForwarder:  mapForwarder{inner: m},
+		cluster:       cluster,
+	}
+}
+
+func (m *hpaClusterMap) CanCross(col int, row int) bool {
+	if !m.cluster.Contains(col, row) {
+		return false
+	}
+
+	return m.NavigationMap.CanCross(col, row)
+}
+
+func (m *hpaClusterMap) GetEdgeGValue(fromCol int, fromRow int, toCol int, toRow int) (uint32, bool) {
+	if !m.CanCross(toCol, toRow) {
+		return 0, false
+	}
+
+	return getEdgeGValue(m.NavigationMap, fromCol, fromRow, toCol, toRow)
+}
+
+//========================
+//      Hpa
+//========================
+type hpaBorder struct {
+	clusterIdx int
+	bVertical  bool
+}
+
+type hpaTransition struct {
+	grid1 Grid
+	grid2 Grid
+}
+
+type Hpa struct {
+	m           NavigationMap
+	clusterSize int
+	clusterCols int
+	clusterRows int
+	clusters    []*HpaCluster
+	transitions map[hpaBorder][]*hpaTransition
+	links       []*Link
+	finder      PathFinder
+	abstract    *hpaAbstractFinder
+}
+
+// the finder tells its heuristic
+type heuristicFinder interface {
+	GetHeuristic() HeuristicFunc
+}
+
+// the finder returns its nodes as the path without copying
+type pathReuseFinder interface {
+	SetPathReuse(bPathReuse bool)
+}
+
+// newFinder create the finder for searching inside a cluster, such as AStar or Jps,
+// the abstract graph is searched with the same heuristic, so it stays admissible
+// for the oblique moves. panic with ErrUnboundedMap if the map is unbounded
+func NewHpa(m NavigationMap, clusterSize int, newFinder func() PathFinder) *Hpa {
+	checkBoundedMap(m)
+	if clusterSize <= 0 {
+		clusterSize = 1
+	}
+
+	h := &Hpa{
+		m:           m,
+		clusterSize: clusterSize,
+		transitions: make(map[hpaBorder][]*hpaTransition),
+		finder:      newFinder(),
+	}
+
+	// the segments are copied by refinePath
+	h.abstract = newHpaAbstractFinder(h)
+	h.abstract.SetPathReuse(true)
+	if finder, ok := h.finder.(pathReuseFinder); ok {
+		finder.SetPathReuse(true)
+	}
+
+	finder, ok := h.finder.(heuristicFinder)
+	if ok {
+		h.abstract.SetHeuristic(finder.GetHeuristic())
+	} else {
+		// don't know how the finder moves
+		h.abstract.SetHeuristic(ZeroHeuristic)
+	}
+
+	h.Build()
+	return h
+}
+
+func (h *Hpa) Build() {
+	maxCol, maxRow := h.m.GetColRow()
+	h.clusterCols = (int(maxCol) + h.clusterSize - 1) / h.clusterSize
+	h.clusterRows = (int(maxRow) + h.clusterSize - 1) / h.clusterSize
+	h.clusters = make([]*HpaCluster, 0, h.clusterCols*h.clusterRows)
+	h.transitions = make(map[hpaBorder][]*hpaTransition)
+
+	for cy := 0; cy < h.clusterRows; cy++ {
+		for cx := 0; cx < h.clusterCols; cx++ {
+			left := cx * h.clusterSize
+			top := cy * h.clusterSize
+			right := minInt(left+h.clusterSize, int(maxCol))
+			bottom := minInt(top+h.clusterSize, int(maxRow))
+			h.clusters = append(h.clusters, NewHpaCluster(left, top, right, bottom))
+		}
+	}
+
+	for cy := 0; cy < h.clusterRows; cy++ {
+		for cx := 0; cx < h.clusterCols; cx++ {
+			h.buildBorder(cx, cy, true)
+			h.buildBorder(cx, cy, false)
+		}
+	}
+
+	h.collectLinks()
+	for _, c := range h.clusters {
+		h.buildCluster(c)
+	}
+}
+
+func (h *Hpa) Reset() {
+	h.finder.Reset()
+	h.abstract.Reset()
+}
+
+func (h *Hpa) GetClusterSize() int {
+	return h.clusterSize
+}
+
+func (h *Hpa) GetCluster(col int, row int) (*HpaCluster, bool) {
+	idx, ok := h.getClusterIndex(col, row)
+	if !ok {
+		return nil, false
+	}
+
+	return h.clusters[idx], true
+}
+
+// rebuild the clusters touched by the changed grid
+func (h *Hpa) UpdateGrid(col int, row int) {
+	idx, ok := h.getClusterIndex(col, row)
+	if !ok {
+		return
+	}
+
+	c := h.clusters[idx]
+	cx := idx % h.clusterCols
+	cy := idx / h.clusterCols
+	dirtyClusters := []*HpaCluster{c}
+
+	// left border
+	if col == c.left && cx > 0 {
+		h.buildBorder(cx-1, cy, true)
+		dirtyClusters = append(dirtyClusters, h.clusters[idx-1])
+	}
+
+	// right border
+	if col == c.right-1 && cx < h.clusterCols-1 {
+		h.buildBorder(cx, cy, true)
+		dirtyClusters = append(dirtyClusters, h.clusters[idx+1])
+	}
+
+	// top border
+	if row == c.top && cy > 0 {
+		h.buildBorder(cx, cy-1, false)
+		dirtyClusters = append(dirtyClusters, h.clusters[idx-h.clusterCols])
+	}
+
+	// bottom border
+	if row == c.bottom-1 && cy < h.clusterRows-1 {
+		h.buildBorder(cx, cy, false)
+		dirtyClusters = append(dirtyClusters, h.clusters[idx+h.clusterCols])
+	}
+
+	for _, dirty := range dirtyClusters {
+		h.buildCluster(dirty)
+	}
+}
+
+// m must be the map the hpa is built on, the other maps are not found
+func (h *Hpa) FindPath(m NavigationMap, startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	if m != h.m {
+		return nil, false
+	}
+
+	if !m.CanCross(startGrid.Col, startGrid.Row) || !m.CanCross(dstGrid.Col, dstGrid.Row) {
+		return nil, false
+	}
+
+	if startGrid.IsSameGrid(dstGrid) {
+		return []PathNode{NewBasePathNode(nil, nil, 0, startGrid.Col, startGrid.Row)}, true
+	}
+
+	startCluster, ok := h.GetCluster(startGrid.Col, startGrid.Row)
+	if !ok {
+		return nil, false
+	}
+
+	dstCluster, ok := h.GetCluster(dstGrid.Col, dstGrid.Row)
+	if !ok {
+		return nil, false
+	}
+
+	// in the same cluster, try to find path directly
+	if startCluster == dstCluster {
+		fullPath, ok := h.refinePath(m, []*Grid{startGrid, dstGrid})
+		if ok {
+			return fullPath, true
+		}
+	}
+
+	// search the abstract graph
+	h.abstract.Reset()
+	h.abstract.setQuery(m, startGrid, startCluster, dstGrid, dstCluster)
+	abstractPath, ok := h.abstract.FindPath(m, startGrid, dstGrid)
+	if !ok {
+		return nil, false
+	}
+
+	grids := make([]*Grid, 0, len(abstractPath))
+	for _, node := range abstractPath {
+		grids = append(grids, node.GetGrid())
+	}
+
+	return h.refinePath(m, grids)
+}
+
+func (h *Hpa) getClusterIndex(col int, row int) (int, bool) {
+	if col < 0 || row < 0 {
+		return 0, false
+	}
+
+	cx := col / h.clusterSize
+	cy := row / h.clusterSize
+	if cx >= h.clusterCols || cy >= h.clusterRows {
+		return 0, false
+	}
+
+	return cy*h.clusterCols + cx, true
+}
+
+// find the transitions on the right (vertical) or bottom border of a cluster
+func (h *Hpa) buildBorder(cx int, cy int, bVertical bool) {
+	idx := cy*h.clusterCols + cx
+	border := hpaBorder{clusterIdx: idx, bVertical: bVertical}
+	delete(h.transitions, border)
+
+	c := h.clusters[idx]
+	if bVertical && cx >= h.clusterCols-1 {
+		return
+	}
+
+	if !bVertical && cy >= h.clusterRows-1 {
+		return
+	}
+
+	transitions := make([]*hpaTransition, 0)
+	gridAt := func(i int) (Grid, Grid) {
+		if bVertical {
+			return Grid{Col: c.right - 1, Row: c.top + i}, Grid{Col: c.right, Row: c.top + i}
+		}
+
+		return Grid{Col: c.left + i, Row: c.bottom - 1}, Grid{Col: c.left + i, Row: c.bottom}
+	}
+
+	borderLen := c.bottom - c.top
+	if !bVertical {
+		borderLen = c.right - c.left
+	}
+
+	runStart := -1
+	for i := 0; i <= borderLen; i++ {
+		bOpen := false
+		if i < borderLen {
+			grid1, grid2 := gridAt(i)
+			bOpen = h.m.CanCross(grid1.Col, grid1.Row) && h.m.CanCross(grid2.Col, grid2.Row)
+		}
+
+		if bOpen {
+			if runStart < 0 {
+				runStart = i
+			}
+
+			continue
+		}
+
+		if runStart < 0 {
+			continue
+		}
+
+		runEnd := i - 1
+		if runEnd-runStart+1 < HpaMaxSingleTransitionLen {
+			grid1, grid2 := gridAt((runStart + runEnd) / 2)
+			transitions = append(transitions, &hpaTransition{grid1: grid1, grid2: grid2})
+		} else {
+			grid1, grid2 := gridAt(runStart)
+			transitions = append(transitions, &hpaTransition{grid1: grid1, grid2: grid2})
+			grid1, grid2 = gridAt(runEnd)
+			transitions = append(transitions, &hpaTransition{grid1: grid1, grid2: grid2})
+		}
+
+		runStart = -1
+	}
+
+	h.transitions[border] = transitions
+}
+
+// the links between clusters, they are collected only by Build
+func (h *Hpa) collectLinks() {
+	h.links = h.links[:0]
+	maxCol, maxRow := h.m.GetColRow()
+	for row := 0; row < int(maxRow); row++ {
+		for col := 0; col < int(maxCol); col++ {
+			for _, link := range getLinks(h.m, col, row) {
+				fromIdx, _ := h.getClusterIndex(link.From.Col, link.From.Row)
+				toIdx, ok := h.getClusterIndex(link.To.Col, link.To.Row)
+				if ok && fromIdx != toIdx {
+					h.links = append(h.links, link)
+				}
+			}
+		}
+	}
+}
+
+func (h *Hpa) getClusterTransitions(idx int) []*hpaTransition {
+	cx := idx % h.clusterCols
+	cy := idx / h.clusterCols
+	transitions := make([]*hpaTransition, 0)
+	transitions = append(transitions, h.transitions[hpaBorder{clusterIdx: idx, bVertical: true}]...)
+	transitions = append(transitions, h.transitions[hpaBorder{clusterIdx: idx, bVertical: false}]...)
+	if cx > 0 {
+		transitions = append(transitions, h.transitions[hpaBorder{clusterIdx: idx - 1, bVertical: true}]...)
+	}
+
+	if cy > 0 {
+		transitions = append(transitions, h.transitions[hpaBorder{clusterIdx: idx - h.clusterCols, bVertical: false}]...)
+	}
+
+	return transitions
+}
+
+func (h *Hpa) buildCluster(c *HpaCluster) {
+	idx, _ := h.getClusterIndex(c.left, c.top)
+	c.nodes = make(map[Grid]*HpaNode)
+
+	// entrances
+	for _, t := range h.getClusterTransitions(idx) {
+		inner, outer := t.grid1, t.grid2
+		if !c.Contains(inner.Col, inner.Row) {
+			inner, outer = outer, inner
+		}
+
+		node := c.addNode(inner.Col, inner.Row)
+		gValue, ok := getEdgeGValue(h.m, inner.Col, inner.Row, outer.Col, outer.Row)
+		if ok {
+			node.interEdges = append(node.interEdges, &HpaEdge{Grid: outer, GValue: gValue})
+		}
+	}
+
+	// both ends of the links between clusters
+	for _, link := range h.links {
+		if c.Contains(link.To.Col, link.To.Row) && h.m.CanCross(link.To.Col, link.To.Row) {
+			c.addNode(link.To.Col, link.To.Row)
+		}
+
+		if !c.Contains(link.From.Col, link.From.Row) || !h.m.CanCross(link.From.Col, link.From.Row) {
+			continue
+		}
+
+		node := c.addNode(link.From.Col, link.From.Row)
+		if h.m.CanCross(link.To.Col, link.To.Row) {
+			node.interEdges = append(node.interEdges, &HpaEdge{Grid: link.To, GValue: link.GValue})
+		}
+	}
+
+	// intra cluster distances, the edge costs and the links may be one-way,
+	// so search from each node
+	for _, from := range c.nodes {
+		from.intraEdges = h.getClusterEdges(h.m, c, from.grid)
+	}
+}
+
+// the edges from the grid to the other nodes of the cluster, found by one search over the
+// cluster if the finder moves are known, otherwise by the finder for each node
+func (h *Hpa) getClusterEdges(m NavigationMap, c *HpaCluster, startGrid *Grid) []*HpaEdge {
+	edges := make([]*HpaEdge, 0, len(c.nodes))
+	costs, bSearched := h.searchCluster(m, c, startGrid)
+	for _, node := range c.nodes {
+		if node.grid.IsSameGrid(startGrid) {
+			continue
+		}
+
+		gValue, ok := costs[*node.grid]
+		if !bSearched {
+			gValue, ok = h.getClusterDistance(m, c, startGrid, node.grid)
+		}
+
+		if ok {
+			edges = append(edges, &HpaEdge{Grid: *node.grid, GValue: gValue})
+		}
+	}
+
+	return edges
+}
+
+// the min g values from the grid to all the grids of the cluster by dijkstra, the moves
+// are the same as the finder, return false if the finder moves are unknown
+func (h *Hpa) searchCluster(m NavigationMap, c *HpaCluster, startGrid *Grid) (map[Grid]uint32, bool) {
+	connectivity := Connectivity4
+	switch finder := h.finder.(type) {
+	case *AStar:
+	case obliqueMoveFinder:
+		connectivity = Connectivity8
+		if finder.CanObliqueMove() {
+			connectivity = Connectivity8CornerCut
+		}
+	default:
+		return nil, false
+	}
+
+	clusterMap := newHpaClusterMap(m, c)
+	costs := map[Grid]uint32{*startGrid: 0}
+	open := &gridCostHeap{}
+	relax := func(to Grid, cost uint32, addGValue uint32) {
+		newCost := uint64(cost) + uint64(addGValue)
+		oldCost, ok := costs[to]
+		if newCost > math.MaxUint32 || (ok && uint64(oldCost) <= newCost) {
+			return
+		}
+
+		costs[to] = uint32(newCost)
+		heap.Push(open, gridCostItem{grid: to, cost: uint32(newCost)})
+	}
+
+	heap.Push(open, gridCostItem{grid: *startGrid, cost: 0})
+	for open.Len() > 0 {
+		item := heap.Pop(open).(gridCostItem)
+		if item.cost != costs[item.grid] {
+			continue
+		}
+
+		grid := item.grid
+		if connectivity == Connectivity4 {
+			// AStar moves to the neighbours of the map
+			for _, next := range getNeighbours(clusterMap, grid.Col, grid.Row) {
+				addGValue, ok := getEdgeGValue(clusterMap, grid.Col, grid.Row, next.Col, next.Row)
+				if ok {
+					relax(*next, item.cost, addGValue)
+				}
+			}
+		} else {
+			for _, vec := range ringVectors {
+				addGValue, ok := getMoveGValue(clusterMap, connectivity, grid.Col, grid.Row, vec)
+				if ok {
+					relax(Grid{Col: grid.Col + vec.X, Row: grid.Row + vec.Y}, item.cost, addGValue)
+				}
+			}
+		}
+
+		for _, link := range getLinks(clusterMap, grid.Col, grid.Row) {
+			if clusterMap.CanCross(link.To.Col, link.To.Row) {
+				relax(link.To, item.cost, link.GValue)
+			}
+		}
+	}
+
+	return costs, true
+}
+
+// return the g value of the path from start to dest, it is summed by the finder,
+// so the oblique moves and the links cost the same as the finder charges
+func (h *Hpa) getClusterDistance(m NavigationMap, c *HpaCluster, startGrid *Grid, dstGrid *Grid) (uint32, bool) {
+	h.finder.Reset()
+	fullPath, ok := h.finder.FindPath(newHpaClusterMap(m, c), startGrid, dstGrid)
+	if !ok {
+		return 0, false
+	}
+
+	return fullPath[len(fullPath)-1].GetMinGValue() - fullPath[0].GetMinGValue(), true
+}
+
+func (h *Hpa) refinePath(m NavigationMap, grids []*Grid) ([]PathNode, bool) {
+	startGrid := grids[0]
+	var node PathNode = NewBasePathNode(nil, nil, 0, startGrid.Col, startGrid.Row)
+	fullPath := []PathNode{node}
+
+	for i := 1; i < len(grids); i++ {
+		from := grids[i-1]
+		to := grids[i]
+		c, _ := h.GetCluster(from.Col, from.Row)
+
+		// transition or link between clusters
+		if !c.Contains(to.Col, to.Row) {
+			gValue := node.GetMinGValue() + getHopGValue(m, from, to)
+			node = NewBasePathNode(node, nil, gValue, to.Col, to.Row)
+			fullPath = append(fullPath, node)
+			continue
+		}
+
+		h.finder.Reset()
+		segment, ok := h.finder.FindPath(newHpaClusterMap(m, c), from, to)
+		if !ok {
+			return nil, false
+		}
+
+		for j := 1; j < len(segment); j++ {
+			grid := segment[j].GetGrid()
+			gValue := node.GetMinGValue() + segment[j].GetMinGValue() - segment[j-1].GetMinGValue()
+			node = NewBasePathNode(node, nil, gValue, grid.Col, grid.Row)
+			fullPath = append(fullPath, node)
+		}
+	}
+
+	return fullPath, true
+}
+
+// g value of the cheapest link from one grid to the other, or the edge between the neighbours
+func getHopGValue(m NavigationMap, from *Grid, to *Grid) uint32 {
+	bLinked := false
+	gValue := uint32(0)
+	for _, link := range getLinks(m, from.Col, from.Row) {
+		if link.To.IsSameGrid(to) && (!bLinked || link.GValue < gValue) {
+			bLinked = true
+			gValue = link.GValue
+		}
+	}
+
+	if bLinked {
+		return gValue
+	}
+
+	gValue, _ = getEdgeGValue(m, from.Col, from.Row, to.Col, to.Row)
+	return gValue
+}
+
+// g value of moving along a straight or oblique line, used for sparse paths like jps
+func getLineGValue(m NavigationMap, from *Grid, to *Grid) uint32 {
+	stepX := signInt(to.Col - from.Col)
+	stepY := signInt(to.Row - from.Row)
+	gValue := uint32(0)
+	col := from.Col
+	row := from.Row
+	for col != to.Col || row != to.Row {
+		preCol := col
+		preRow := row
+		if col != to.Col {
+			col += stepX
+		}
+
+		if row != to.Row {
+			row += stepY
+		}
+
+		addGValue, _ := getEdgeGValue(m, preCol, preRow, col, row)
+		gValue += addGValue
+	}
+
+	return gValue
+}
+
+func signInt(v int) int {
+	if v > 0 {
+		return 1
+	}
+
+	if v < 0 {
+		return -1
+	}
+
+	return 0
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+//========================
+//   hpaAbstractFinder
+//========================
+// search on the abstract graph, the start and dest grid are linked to
+// the entrances of their clusters for each query
+type hpaAbstractFinder struct {
+	*BasePathFinder
+	hpa        *Hpa
+	startGrid  Grid
+	startEdges []*HpaEdge
+	dstGrid    Grid
+	dstEdges   map[Grid]uint32
+}
+
+func newHpaAbstractFinder(h *Hpa) *hpaAbstractFinder {
+	f := &hpaAbstractFinder{
+		hpa:      h,
+		dstEdges: make(map[Grid]uint32),
+	}
+
+	f.BasePathFinder = NewBasePathFinder(f)
+	return f
+}
+
+func (f *hpaAbstractFinder) setQuery(m NavigationMap, startGrid *Grid, startCluster *HpaCluster, dstGrid *Grid, dstCluster *HpaCluster) {
+	f.startGrid = *startGrid
+	f.startEdges = f.hpa.getClusterEdges(m, startCluster, startGrid)
+
+	f.dstGrid = *dstGrid
+	f.dstEdges = make(map[Grid]uint32)
+	for _, node := range dstCluster.nodes {
+		gValue, ok := f.hpa.getClusterDistance(m, dstCluster, node.grid, dstGrid)
+		if ok {
+			f.dstEdges[*node.grid] = gValue
+		}
+	}
+}
+
+func (f *hpaAbstractFinder) CreateFirstNode(col int, row int) PathNode {
+	return NewBasePathNode(nil, nil, 0, col, row)
+}
+
+func (f *hpaAbstractFinder) UnfoldGrid(m NavigationMap, dstGrid *Grid, node PathNode) {
+	grid := node.GetGrid()
+
+	if grid.IsSameGrid(&f.startGrid) {
+		f.handleEdges(m, node, f.startEdges)
+	}
+
+	c, ok := f.hpa.GetCluster(grid.Col, grid.Row)
+	if ok {
+		exist, ok := c.GetNode(grid.Col, grid.Row)
+		if ok {
+			f.handleEdges(m, node, exist.intraEdges)
+			f.handleEdges(m, node, exist.interEdges)
+		}
+	}
+
+	gValue, ok := f.dstEdges[*grid]
+	if ok {
+		f.handleEdge(m, node, &f.dstGrid, gValue)
+	}
+
+	f.AddNodeToCloseList(node)
+}
+
+func (f *hpaAbstractFinder) handleEdges(m NavigationMap, parent PathNode, edges []*HpaEdge) {
+	for _, edge := range edges {
+		grid := edge.Grid
+		f.handleEdge(m, parent, &grid, edge.GValue)
+	}
+}
+
+func (f *hpaAbstractFinder) handleEdge(m NavigationMap, parent PathNode, grid *Grid, addGValue uint32) {
+	minGValue := parent.GetMinGValue() + addGValue
+	if f.UpdateExistList(m, grid.Col, grid.Row, parent, nil, minGValue) {
+		return
+	}
+
+	node := NewBasePathNode(parent, nil, minGValue, grid.Col, grid.Row)
+	f.AddNodeToOpenList(node)
+}
@@ -0,0 +1,120 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+func TestHpaHeuristic(t *testing.T) {
+	m := newTestMap(t, "........", "........", "........", "........")
+	cases := []struct {
+		name      string
+		newFinder func() PathFinder
+		want      uint32
+	}{
+		{"astar", func() PathFinder { return NewAStar() }, 6},
+		{"jps8", func() PathFinder { return NewJps(0, true) }, 3},
+	}
+
+	for _, c := range cases {
+		h := NewHpa(m, 2, c.newFinder)
+		hValue := h.abstract.GetHeuristic()(NewGrid(0, 0), NewGrid(3, 3), 1)
+		if hValue != c.want {
+			t.Errorf("%s: got h value %d, want %d", c.name, hValue, c.want)
+		}
+	}
+}
+
+// the border between the clusters has one open transition with a costly edge
+func TestHpaEdgeCost(t *testing.T) {
+	m := NewEdgeLayer(newTestMap(t, "...#...", "......."))
+	m.SetEdge(NewGrid(2, 1), NewGrid(3, 1), 20, true)
+	m.SetEdge(NewGrid(3, 1), NewGrid(4, 1), 20, true)
+	h := NewHpa(m, 3, func() PathFinder { return NewAStar() })
+
+	// (0, 0) -> (0, 1) -> (1, 1) -> (2, 1) -> (3, 1) -> (4, 1) -> (4, 0) -> (5, 0) -> (6, 0)
+	gValue, ok := getPathGValue(t, h, m, NewGrid(0, 0), NewGrid(6, 0))
+	if !ok || gValue != 46 {
+		t.Errorf("got g value %d (%v), want 46", gValue, ok)
+	}
+}
+
+// the clusters are split by the wall, only the link goes across
+func TestHpaLinks(t *testing.T) {
+	links := NewLinkLayer(newTestMap(t, "...#...", "...#...", "...#..."))
+	links.AddLink(NewGrid(1, 0), NewGrid(5, 2), 10, LinkTypeTeleport, false)
+	h := NewHpa(links, 3, func() PathFinder { return NewAStar() })
+
+	// (0, 0) -> (1, 0) -> link -> (5, 2) -> (5, 1) -> (6, 1) -> (6, 2), the transition
+	// between the right clusters is in the middle of the border
+	gValue, ok := getPathGValue(t, h, links, NewGrid(0, 0), NewGrid(6, 2))
+	if !ok || gValue != 14 {
+		t.Errorf("got g value %d (%v), want 14", gValue, ok)
+	}
+
+	if _, ok := h.FindPath(links, NewGrid(6, 2), NewGrid(0, 0)); ok {
+		t.Error("path found back through the one-way link")
+	}
+}
+
+// an unknown finder, the hpa searches the clusters by it
+type wrappedFinder struct {
+	PathFinder
+}
+
+// the edges found by one search from each node are the same as the finder finds
+func TestHpaClusterEdges(t *testing.T) {
+	edges := NewEdgeLayer(newRandomTestMap(7, 24, 24, 0.25, 5))
+	for i := 0; i < 24; i++ {
+		// one-way, the reverse edges keep the default cost
+		edges.SetEdge(NewGrid(i, 10), NewGrid(i, 11), 9, true)
+		edges.SetEdge(NewGrid(12, i), NewGrid(13, i), 7, true)
+	}
+
+	m := NewLinkLayer(edges)
+	m.AddLink(NewGrid(2, 2), NewGrid(5, 6), 3, LinkTypeTeleport, false)
+	m.AddLink(NewGrid(1, 9), NewGrid(20, 20), 4, LinkTypeTeleport, true)
+	newFinders := map[string]func() PathFinder{
+		"astar":   func() PathFinder { return NewAStar() },
+		"jps":     func() PathFinder { return NewJps(0, false) },
+		"jps8":    func() PathFinder { return NewJps(0, true) },
+		"wrapped": func() PathFinder { return &wrappedFinder{PathFinder: NewAStar()} },
+	}
+
+	for name, newFinder := range newFinders {
+		h := NewHpa(m, 8, newFinder)
+		for _, c := range h.clusters {
+			for _, from := range c.nodes {
+				want := make(map[Grid]uint32)
+				for _, to := range c.nodes {
+					if gValue, ok := h.getClusterDistance(m, c, from.grid, to.grid); ok && from != to {
+						want[*to.grid] = gValue
+					}
+				}
+
+				if len(from.intraEdges) != len(want) {
+					t.Errorf("%s: got %d edges from %v, want %d", name, len(from.intraEdges), from.grid, len(want))
+				}
+
+				for _, edge := range from.intraEdges {
+					if gValue, ok := want[edge.Grid]; !ok || gValue != edge.GValue {
+						t.Errorf("%s: got edge %v -> %v g value %d, want %d (%v)", name, from.grid, edge.Grid, edge.GValue, gValue, ok)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestHpaOtherMap(t *testing.T) {
+	m := newTestMap(t, "....", "....")
+	h := NewHpa(m, 2, func() PathFinder { return NewAStar() })
+	if _, ok := h.FindPath(NewGridMapFrom(m), NewGrid(0, 0), NewGrid(3, 1)); ok {
+		t.Error("path found on the map the hpa is not built on")
+	}
+
+	if _, ok := h.FindPath(m, NewGrid(0, 0), NewGrid(3, 1)); !ok {
+		t.Error("no path")
+	}
+}
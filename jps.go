@@ -41,6 +41,28 @@ func NewJpsNode(parent PathNode, vecParent *Vector, minGValue uint32, col int, r
 	}
 }
 
+func (n *JpsNode) Init(parent PathNode, vecParent *Vector, minGValue uint32, col int, row int, bJumpPoint bool) {
+	n.BasePathNode.Init(parent, vecParent, minGValue, col, row)
+	n.vecNeighbour = nil
+	n.bOrthogonalUnfold = false
+	n.bObliqueUnfold = false
+	n.bJumpPoint = bJumpPoint
+}
+
+// reuse a node of the finder if there is one
+func newPooledJpsNode(f *BasePathFinder, parent PathNode, vecParent *Vector, minGValue uint32, col int, row int, bJumpPoint bool) *JpsNode {
+	pooled, ok := f.GetPooledNode()
+	if ok {
+		node := pooled.(*JpsNode)
+		node.Init(parent, vecParent, minGValue, col, row, bJumpPoint)
+		return node
+	}
+
+	node := NewJpsNode(parent, vecParent, minGValue, col, row, bJumpPoint)
+	f.AddNodeToPool(node)
+	return node
+}
+
 func (n *JpsNode) SetNeighbourVector(vec *Vector) {
 	n.vecNeighbour = vec
 }
@@ -65,6 +87,11 @@ func (n *JpsNode) IsObliqueUnfold() bool {
 	return n.bObliqueUnfold
 }
 
+func (n *JpsNode) ResetUnfold() {
+	n.bOrthogonalUnfold = false
+	n.bObliqueUnfold = false
+}
+
 func (n *JpsNode) SetJumpPoint() {
 	n.bJumpPoint = true
 }
@@ -76,10 +103,20 @@ func (n *JpsNode) IsJumpPoint() bool {
 //========================
 //      Jps
 //========================
+// the jump point search keeps optimal on weighted maps by taking a grid
+// whose neighbours have different g values as a jump point, and unfold all
+// the directions of it
+//
+// maxOrthogonalDeep limits the grids of one scan, the grid at the limit is taken as
+// a jump point, 0 means no limit, but UnboundedMaxOrthogonalDeep on an unbounded map
 type Jps struct {
 	*BasePathFinder
 	maxOrthogonalDeep uint32
 	canObliqueMove    bool
+	// the buffers kept between the searches
+	orthogonalVectors []*Vector
+	obliqueVectors    []*Vector
+	neighbourVectors  []*Vector
 }
 
 func NewJps(maxOrthogonalDeep uint32, canObliqueMove bool) *Jps {
@@ -89,11 +126,20 @@ func NewJps(maxOrthogonalDeep uint32, canObliqueMove bool) *Jps {
 	}
 
 	j.BasePathFinder = NewBasePathFinder(j)
+	if canObliqueMove {
+		j.SetHeuristic(ChebyshevHeuristic)
+	}
+
 	return j
 }
 
+// the oblique move cuts the corners, otherwise it goes through a side grid
+func (j *Jps) CanObliqueMove() bool {
+	return j.canObliqueMove
+}
+
 func (j *Jps) CreateFirstNode(col int, row int) PathNode {
-	return NewJpsNode(nil, VecStart, 0, col, row, true)
+	return newPooledJpsNode(j.BasePathFinder, nil, VecStart, 0, col, row, true)
 }
 
 func (j *Jps) UnfoldGrid(m NavigationMap, dstGrid *Grid, node PathNode) {
@@ -108,321 +154,319 @@ func (j *Jps) UnfoldGrid(m NavigationMap, dstGrid *Grid, node PathNode) {
 }
 
 func (j *Jps) findJumpPoint(m NavigationMap, dstGrid *Grid, startNode *JpsNode) {
-	// Orthogonal
+	// unfold all directions for the start grid and the grid with g value changed
+	grid := startNode.GetGrid()
+	bUnfoldAll := (startNode.GetParentVector() == VecStart || j.hasGValueChange(m, grid.Col, grid.Row))
+
+	// orthogonal unfold
 	if !startNode.IsOrthogonalUnfold() {
-		bFindOut := j.findJumpPointOrthogonal(m, dstGrid, startNode)
+		j.findJumpPointOrthogonal(m, dstGrid, startNode, bUnfoldAll)
 		startNode.SetOrthogonalUnfold()
-		if bFindOut && j.tryAddToOpenList(startNode) {
-			return
-		}
 	}
 
 	// oblique unfold
 	if !startNode.IsObliqueUnfold() {
-		j.findJumpPointOblique(m, dstGrid, startNode)
+		j.findJumpPointOblique(m, dstGrid, startNode, bUnfoldAll)
 		startNode.SetObliqueUnfold()
 	}
+
+	// links
+	j.findJumpPointLink(m, startNode)
 }
 
-func (j *Jps) findJumpPointOrthogonal(m NavigationMap, dstGrid *Grid, startNode *JpsNode) bool {
-	bFindOut := false
-	orthogonalVectors := j.getOrthogonalVectors(startNode)
+func (j *Jps) findJumpPointOrthogonal(m NavigationMap, dstGrid *Grid, startNode *JpsNode, bUnfoldAll bool) {
+	orthogonalVectors := j.getOrthogonalVectors(startNode, bUnfoldAll)
 	for _, vec := range orthogonalVectors {
-		ok := j.findJumpPointLoop(m, dstGrid, startNode, vec)
-		bFindOut = (bFindOut || ok)
-		if j.lastNode != nil {
-			break
+		col, row, gValue, ok := j.findJumpPointLoop(m, dstGrid, startNode.GetGrid(), startNode.GetMinGValue(), vec)
+		if ok {
+			j.handleFindout(m, startNode, vec, col, row, gValue)
 		}
 	}
-
-	return bFindOut
 }
 
-func (j *Jps) getOrthogonalVectors(startNode *JpsNode) []*Vector {
-	vectors := make([]*Vector, 0)
+func (j *Jps) getOrthogonalVectors(startNode *JpsNode, bUnfoldAll bool) []*Vector {
+	vectors := j.orthogonalVectors[:0]
 	vecParent := startNode.GetParentVector()
-	if vecParent == VecStart || vecParent.X > 0 {
+	if bUnfoldAll || vecParent.X > 0 {
 		vectors = append(vectors, VecRight)
 	}
 
-	if vecParent == VecStart || vecParent.X < 0 {
+	if bUnfoldAll || vecParent.X < 0 {
 		vectors = append(vectors, VecLeft)
 	}
 
-	if vecParent == VecStart || vecParent.Y > 0 {
+	if bUnfoldAll || vecParent.Y > 0 {
 		vectors = append(vectors, VecDown)
 	}
 
-	if vecParent == VecStart || vecParent.Y < 0 {
+	if bUnfoldAll || vecParent.Y < 0 {
 		vectors = append(vectors, VecUp)
 	}
 
+	j.orthogonalVectors = vectors
 	return vectors
 }
 
-func (j *Jps) findJumpPointLoop(m NavigationMap, dstGrid *Grid, startNode *JpsNode, vec *Vector) bool {
-	maxCol, maxRow := m.GetColRow()
-	endCol := int(maxCol - 1)
-	if vec.X < 0 {
-		endCol = 0
-	}
-
-	endRow := int(maxRow - 1)
-	if vec.Y < 0 {
-		endRow = 0
-	}
-
-	bFind := false
-	// bFindDst := false
-	gValue := startNode.GetMinGValue()
-	grid := startNode.GetGrid()
+// scan from the grid along the orthogonal vector, return the jump point
+func (j *Jps) findJumpPointLoop(m NavigationMap, dstGrid *Grid, grid *Grid, gValue uint32, vec *Vector) (int, int, uint32, bool) {
 	col := grid.Col
 	row := grid.Row
-	if startNode.IsJumpPoint() {
-		col = grid.Col + vec.X
-		row = grid.Row + vec.Y
-		gValue += m.GetGValue(col, row)
-	}
-
-	for {
-		vecParent := vec
-		if grid.IsSameGrid2(col, row) {
-			vecParent = startNode.GetParentVector()
-		}
+	for deep := uint32(1); ; deep++ {
+		col += vec.X
+		row += vec.Y
+		j.stats.JumpScanLength++
+		j.AddScannedGrid(col, row)
 
-		// find dest
-		if dstGrid.IsSameGrid2(col, row) {
-			j.handleFindout(m, startNode, vecParent, nil, col, row, gValue, true)
-			bFind = true
-			// bFindDst = true
-			break
+		// end
+		if !isInMapBounds(m, col, row) {
+			return 0, 0, 0, false
 		}
 
 		// can't find any more
-		if !m.CanCross(col, row) {
-			break
+		addGValue, ok := getEdgeGValue(m, col-vec.X, row-vec.Y, col, row)
+		if !ok {
+			return 0, 0, 0, false
 		}
 
-		// end
-		if (vec.X != 0 && col == endCol) || (vec.Y != 0 && row == endRow) {
-			break
+		gValue += addGValue
+
+		// find dest
+		if dstGrid.IsSameGrid2(col, row) {
+			return col, row, gValue, true
 		}
 
 		// find jump point
-		vecNeighbour, ok := j.getNeighbour(m, vecParent, col, row)
-		if ok {
-			// println("startNode(", grid.Col, ", ", grid.Row, ")")
-			// println("grid(", col, ", ", row, ") vec: ", vecNeighbour.X, ", ", vecNeighbour.Y)
-			j.handleFindout(m, startNode, vecParent, vecNeighbour, col, row, gValue, false)
-			bFind = true
+		if j.isJumpPoint(m, vec, col, row) {
+			return col, row, gValue, true
+		}
 
-			if !grid.IsSameGrid2(col, row) {
-				break
-			}
+		// too deep, continue from here later
+		if j.isTooDeep(m, deep) {
+			return col, row, gValue, true
 		}
+	}
+}
 
-		col += vec.X
-		row += vec.Y
-		gValue += m.GetGValue(col, row)
+func (j *Jps) isTooDeep(m NavigationMap, deep uint32) bool {
+	maxDeep := j.maxOrthogonalDeep
+	if maxDeep == 0 && isUnbounded(m) {
+		maxDeep = UnboundedMaxOrthogonalDeep
 	}
 
-	return bFind
+	return maxDeep > 0 && deep >= maxDeep
 }
 
-func (j *Jps) getNeighbour(m NavigationMap, vecParent *Vector, col int, row int) (*Vector, bool) {
-	grid := NewGrid(col, row)
-
-	// right up
-	if vecParent.Y == -1 && vecParent.X <= 0 {
-		if !m.CanCross(col+1, row) && j.canMoveOblique(m, grid, col+1, row-1) {
-			return VecRightUp, true
-		}
+func (j *Jps) isJumpPoint(m NavigationMap, vecParent *Vector, col int, row int) bool {
+	_, ok := j.getNeighbour(m, vecParent, col, row)
+	if ok {
+		return true
 	}
 
-	if vecParent.X == 1 && vecParent.Y >= 0 {
-		if !m.CanCross(col, row-1) && j.canMoveOblique(m, grid, col+1, row-1) {
-			return VecRightUp, true
-		}
+	// the start grid of links
+	if len(getLinks(m, col, row)) > 0 {
+		return true
 	}
 
-	// right down
-	if vecParent.Y == 1 && vecParent.X <= 0 {
-		if !m.CanCross(col+1, row) && j.canMoveOblique(m, grid, col+1, row+1) {
-			return VecRightDown, true
+	// a change of g value works like a forced neighbour
+	return j.hasGValueChange(m, col, row)
+}
+
+// whether the crossable neighbours have different g values with the grid,
+// or any edge around has its own g value
+func (j *Jps) hasGValueChange(m NavigationMap, col int, row int) bool {
+	gValue := m.GetGValue(col, row)
+	for y := row - 1; y <= row+1; y++ {
+		for x := col - 1; x <= col+1; x++ {
+			if hasEdgeCost(m, x, y) {
+				return true
+			}
+
+			if m.CanCross(x, y) && m.GetGValue(x, y) != gValue {
+				return true
+			}
 		}
 	}
 
-	if vecParent.X == 1 && vecParent.Y <= 0 {
-		if !m.CanCross(col, row+1) && j.canMoveOblique(m, grid, col+1, row+1) {
-			return VecRightDown, true
-		}
+	return false
+}
+
+func (j *Jps) getNeighbour(m NavigationMap, vecParent *Vector, col int, row int) (*Vector, bool) {
+	neighbours := j.getNeighbours(m, vecParent, col, row, j.neighbourVectors[:0])
+	j.neighbourVectors = neighbours
+	if len(neighbours) == 0 {
+		return nil, false
 	}
 
-	// left up
-	if vecParent.Y == -1 && vecParent.X >= 0 {
-		if !m.CanCross(col-1, row) && j.canMoveOblique(m, grid, col-1, row-1) {
-			return VecLeftUp, true
+	return neighbours[0], true
+}
+
+// append all the forced neighbours
+func (j *Jps) getNeighbours(m NavigationMap, vecParent *Vector, col int, row int, neighbours []*Vector) []*Vector {
+	grid := &Grid{Col: col, Row: row}
+
+	// right up
+	if (vecParent.Y == -1 && vecParent.X <= 0 && !m.CanCross(col+1, row)) ||
+		(vecParent.X == 1 && vecParent.Y >= 0 && !m.CanCross(col, row-1)) {
+		if j.canMoveOblique(m, grid, col+1, row-1) {
+			neighbours = append(neighbours, VecRightUp)
 		}
 	}
 
-	if vecParent.X == -1 && vecParent.Y >= 0 {
-		if !m.CanCross(col, row-1) && j.canMoveOblique(m, grid, col-1, row-1) {
-			return VecLeftUp, true
+	// right down
+	if (vecParent.Y == 1 && vecParent.X <= 0 && !m.CanCross(col+1, row)) ||
+		(vecParent.X == 1 && vecParent.Y <= 0 && !m.CanCross(col, row+1)) {
+		if j.canMoveOblique(m, grid, col+1, row+1) {
+			neighbours = append(neighbours, VecRightDown)
 		}
 	}
 
-	// left down
-	if vecParent.Y == 1 && vecParent.X >= 0 {
-		if !m.CanCross(col-1, row) && j.canMoveOblique(m, grid, col-1, row+1) {
-			return VecLeftDown, true
+	// left up
+	if (vecParent.Y == -1 && vecParent.X >= 0 && !m.CanCross(col-1, row)) ||
+		(vecParent.X == -1 && vecParent.Y >= 0 && !m.CanCross(col, row-1)) {
+		if j.canMoveOblique(m, grid, col-1, row-1) {
+			neighbours = append(neighbours, VecLeftUp)
 		}
 	}
 
-	if vecParent.X == -1 && vecParent.Y <= 0 {
-		if !m.CanCross(col, row+1) && j.canMoveOblique(m, grid, col-1, row+1) {
-			return VecLeftDown, true
+	// left down
+	if (vecParent.Y == 1 && vecParent.X >= 0 && !m.CanCross(col-1, row)) ||
+		(vecParent.X == -1 && vecParent.Y <= 0 && !m.CanCross(col, row+1)) {
+		if j.canMoveOblique(m, grid, col-1, row+1) {
+			neighbours = append(neighbours, VecLeftDown)
 		}
 	}
 
-	return nil, false
+	return neighbours
 }
 
-func (j *Jps) handleFindout(m NavigationMap, startNode *JpsNode, vecParent *Vector, vecNeighbour *Vector, col int, row int, gValue uint32, bFindDst bool) {
-	startNode.SetJumpPoint()
-	startGrid := startNode.GetGrid()
-	node := startNode
-	if startGrid.IsSameGrid2(col, row) {
-		if j.UpdateExistList(m, col, row, startNode.GetParent(), vecParent, gValue) {
-			return
-		}
-
-	} else {
-		if j.UpdateExistList(m, col, row, startNode, vecParent, gValue) {
-			return
-		}
-
-		node = NewJpsNode(startNode, vecParent, gValue, col, row, true)
-		j.AddNodeToOpenList(node)
+func (j *Jps) handleFindout(m NavigationMap, startNode *JpsNode, vecParent *Vector, col int, row int, gValue uint32) {
+	if j.observer != nil {
+		j.observer.OnJumpPointFound(startNode, col, row, gValue)
 	}
 
-	node.SetNeighbourVector(vecNeighbour)
-
-	if bFindDst {
-		j.lastNode = node
+	// already in open list or close list, update min G value
+	if j.UpdateExistList(m, col, row, startNode, vecParent, gValue) {
+		return
 	}
-}
 
-func (j *Jps) tryAddToOpenList(node *JpsNode) bool {
-	grid := node.GetGrid()
-	_, ok := j.GetOpenNode(grid.Col, grid.Row)
-	if !ok {
-		j.AddNodeToOpenList(node)
-		return true
+	node := newPooledJpsNode(j.BasePathFinder, startNode, vecParent, gValue, col, row, true)
+	vecNeighbour, ok := j.getNeighbour(m, vecParent, col, row)
+	if ok {
+		node.SetNeighbourVector(vecNeighbour)
 	}
 
-	return false
+	j.AddNodeToOpenList(node)
 }
 
-func (j *Jps) findJumpPointOblique(m NavigationMap, dstGrid *Grid, startNode *JpsNode) {
-	obliqueVectors := j.getNextObliqueVectors(startNode)
-	for _, vec := range obliqueVectors {
-		node, ok := j.findNextGridOblique(m, dstGrid, startNode, vec)
-		if !ok {
-			if j.lastNode != nil {
-				break
-			}
-
+// the grid at the end of a link unfolds all directions
+func (j *Jps) findJumpPointLink(m NavigationMap, startNode *JpsNode) {
+	grid := startNode.GetGrid()
+	for _, link := range getLinks(m, grid.Col, grid.Row) {
+		if !m.CanCross(link.To.Col, link.To.Row) {
 			continue
 		}
 
-		j.findJumpPoint(m, dstGrid, node)
-		if j.lastNode != nil {
-			break
+		gValue := startNode.GetMinGValue() + link.GValue
+		if j.UpdateExistListByLink(m, startNode, link, gValue) {
+			continue
 		}
-	}
-}
-
-func (j *Jps) getOVectorsForObliqueParent(startNode *JpsNode) []*Vector {
-	vectors := make([]*Vector, 0)
-	vec := startNode.GetParentVector()
-	vectors = append(vectors, vec)
 
-	if !startNode.IsJumpPoint() {
-		return vectors
+		node := newPooledJpsNode(j.BasePathFinder, startNode, VecStart, gValue, link.To.Col, link.To.Row, true)
+		setNodeLink(node, link)
+		j.AddNodeToOpenList(node)
 	}
+}
 
-	vecNeighbour := startNode.GetNeighbourVector()
-	if vecNeighbour == nil {
-		return vectors
+func (j *Jps) findJumpPointOblique(m NavigationMap, dstGrid *Grid, startNode *JpsNode, bUnfoldAll bool) {
+	obliqueVectors := j.getNextObliqueVectors(m, startNode, bUnfoldAll)
+	for _, vec := range obliqueVectors {
+		col, row, gValue, ok := j.findNextGridOblique(m, dstGrid, startNode.GetGrid(), startNode.GetMinGValue(), vec)
+		if ok {
+			j.handleFindout(m, startNode, vec, col, row, gValue)
+		}
 	}
+}
 
-	if vecNeighbour.X != vec.X || vecNeighbour.Y != vec.Y {
-		vectors = append(vectors, vecNeighbour)
+func (j *Jps) getNextObliqueVectors(m NavigationMap, startNode *JpsNode, bUnfoldAll bool) []*Vector {
+	if bUnfoldAll {
+		return []*Vector{VecLeftUp, VecLeftDown, VecRightUp, VecRightDown}
 	}
 
-	return vectors
-}
-
-func (j *Jps) getNextObliqueVectors(startNode *JpsNode) []*Vector {
+	vectors := j.obliqueVectors[:0]
 	vec := startNode.GetParentVector()
 	if vec.IsOblique() {
-		return j.getOVectorsForObliqueParent(startNode)
+		vectors = append(vectors, vec)
 	}
 
-	vectors := make([]*Vector, 0)
-	if vec.IsEmptyVector() || vec.X == -1 || vec.Y == -1 {
-		vectors = append(vectors, VecLeftUp)
-	}
+	grid := startNode.GetGrid()
+	j.obliqueVectors = j.getNeighbours(m, vec, grid.Col, grid.Row, vectors)
+	return j.obliqueVectors
+}
 
-	if vec.IsEmptyVector() || vec.X == -1 || vec.Y == 1 {
-		vectors = append(vectors, VecLeftDown)
-	}
+// scan from the grid along the oblique vector, return the jump point
+func (j *Jps) findNextGridOblique(m NavigationMap, dstGrid *Grid, grid *Grid, gValue uint32, vec *Vector) (int, int, uint32, bool) {
+	from := &Grid{Col: grid.Col, Row: grid.Row}
+	for deep := uint32(1); ; deep++ {
+		nextCol := from.Col + vec.X
+		nextRow := from.Row + vec.Y
+		j.stats.JumpScanLength++
+		j.AddScannedGrid(nextCol, nextRow)
+
+		// can't cross
+		addGValue := j.getMinGValueOblique(m, from, nextCol, nextRow)
+		if addGValue == math.MaxUint32 {
+			return 0, 0, 0, false
+		}
 
-	if vec.IsEmptyVector() || vec.X == 1 || vec.Y == -1 {
-		vectors = append(vectors, VecRightUp)
-	}
+		gValue += addGValue
+		from.Update(nextCol, nextRow)
 
-	if vec.IsEmptyVector() || vec.X == 1 || vec.Y == 1 {
-		vectors = append(vectors, VecRightDown)
-	}
+		// find dest grid
+		if dstGrid.IsSameGrid(from) {
+			return nextCol, nextRow, gValue, true
+		}
 
-	return vectors
-}
+		// find jump point
+		if j.isJumpPoint(m, vec, nextCol, nextRow) {
+			return nextCol, nextRow, gValue, true
+		}
 
-func (j *Jps) findNextGridOblique(m NavigationMap, dstGrid *Grid, startNode *JpsNode, vec *Vector) (*JpsNode, bool) {
-	parent := j.getParentNode(startNode)
-	grid := startNode.GetGrid()
-	nextCol := grid.Col + vec.X
-	nextRow := grid.Row + vec.Y
+		// a jump point can be found orthogonally
+		if j.hasJumpPointOrthogonal(m, dstGrid, from, vec) {
+			return nextCol, nextRow, gValue, true
+		}
 
-	// can't cross
-	minGValue := j.getMinGValueOblique(m, grid, nextCol, nextRow)
-	if minGValue == math.MaxUint32 {
-		return nil, false
+		// too deep, continue from here later
+		if j.isTooDeep(m, deep) {
+			return nextCol, nextRow, gValue, true
+		}
 	}
+}
 
-	// find dest grid
-	if dstGrid.IsSameGrid2(nextCol, nextRow) {
-		j.lastNode = NewJpsNode(parent, vec, 0, nextCol, nextRow, true)
-		return nil, false
+func (j *Jps) hasJumpPointOrthogonal(m NavigationMap, dstGrid *Grid, grid *Grid, vec *Vector) bool {
+	_, _, _, ok := j.findJumpPointLoop(m, dstGrid, grid, 0, getHorizontalVector(vec))
+	if ok {
+		return true
 	}
 
-	// exist in open or closed list
-	vecParent := parent.GetParentVector()
-	if j.UpdateExistList(m, nextCol, nextRow, parent, vecParent, minGValue) {
-		return nil, false
+	_, _, _, ok = j.findJumpPointLoop(m, dstGrid, grid, 0, getVerticalVector(vec))
+	return ok
+}
+
+func getHorizontalVector(vec *Vector) *Vector {
+	if vec.X > 0 {
+		return VecRight
 	}
 
-	return NewJpsNode(parent, vec, minGValue, nextCol, nextRow, false), true
+	return VecLeft
 }
 
-func (j *Jps) getParentNode(preNode *JpsNode) PathNode {
-	var parent PathNode = preNode
-	if !preNode.IsJumpPoint() {
-		parent = preNode.GetParent()
+func getVerticalVector(vec *Vector) *Vector {
+	if vec.Y > 0 {
+		return VecDown
 	}
 
-	return parent
+	return VecUp
 }
 
 func (j *Jps) canMoveOblique(m NavigationMap, parent *Grid, col int, row int) bool {
@@ -435,24 +479,44 @@ func (j *Jps) getMinGValueOblique(m NavigationMap, parent *Grid, col int, row in
 	}
 
 	if j.canObliqueMove {
-		return m.GetGValue(col, row)
+		addGValue, ok := getEdgeGValue(m, parent.Col, parent.Row, col, row)
+		if !ok {
+			return math.MaxUint32
+		}
+
+		return addGValue
 	}
 
-	addGValue := uint32(math.MaxUint32)
-	if m.CanCross(col, parent.Row) {
-		addGValue = m.GetGValue(col, parent.Row)
+	addGValue, ok := getObliqueGValueBySide(m, parent.Col, parent.Row, col, row)
+	if !ok {
+		return math.MaxUint32
 	}
 
-	if m.CanCross(parent.Col, row) {
-		gValue := m.GetGValue(parent.Col, row)
-		if addGValue > gValue {
-			addGValue = gValue
-		}
+	return addGValue
+}
+
+// an oblique move without cutting the corners goes through one of the side grids,
+// it costs the same as the two orthogonal moves
+func getObliqueGValueBySide(m NavigationMap, fromCol int, fromRow int, col int, row int) (uint32, bool) {
+	addGValue := getGValueBySide(m, fromCol, fromRow, col, fromRow, col, row)
+	gValue := getGValueBySide(m, fromCol, fromRow, fromCol, row, col, row)
+	if addGValue > gValue {
+		addGValue = gValue
+	}
+
+	return addGValue, addGValue != math.MaxUint32
+}
+
+func getGValueBySide(m NavigationMap, fromCol int, fromRow int, sideCol int, sideRow int, col int, row int) uint32 {
+	gValue1, ok := getEdgeGValue(m, fromCol, fromRow, sideCol, sideRow)
+	if !ok {
+		return math.MaxUint32
 	}
 
-	if addGValue == math.MaxUint32 {
+	gValue2, ok := getEdgeGValue(m, sideCol, sideRow, col, row)
+	if !ok {
 		return math.MaxUint32
 	}
 
-	return addGValue + m.GetGValue(col, row)
+	return gValue1 + gValue2
 }
@@ -0,0 +1,481 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"math"
+)
+
+// version 2 appends a crc32 of the bytes before it, version 1 can still be loaded
+const (
+	jpsPlusMagic      = "JPSP"
+	jpsPlusVersion    = 2
+	jpsPlusOldVersion = 1
+	jpsPlusHeaderSize = 14
+	jpsPlusMaxDist    = math.MaxInt16
+)
+
+// the order of directions stored for each grid
+var jpsPlusVectors = []*Vector{
+	VecUp,
+	VecRightUp,
+	VecRight,
+	VecRightDown,
+	VecDown,
+	VecLeftDown,
+	VecLeft,
+	VecLeftUp,
+}
+
+//========================
+//      JpsPlusTable
+//========================
+// for each grid and each direction, a positive distance means a jump point,
+// otherwise it is the negative distance to the wall
+type JpsPlusTable struct {
+	cols           uint32
+	rows           uint32
+	canObliqueMove bool
+	distances      []int16
+}
+
+// panic with ErrUnboundedMap if the map is unbounded
+func NewJpsPlusTable(m NavigationMap, canObliqueMove bool) *JpsPlusTable {
+	checkBoundedMap(m)
+	cols, rows := m.GetColRow()
+	t := &JpsPlusTable{
+		cols:           cols,
+		rows:           rows,
+		canObliqueMove: canObliqueMove,
+		distances:      make([]int16, int(cols)*int(rows)*len(jpsPlusVectors)),
+	}
+
+	t.build(m)
+	return t
+}
+
+// load the binary or the json encoding of the table for the map
+func LoadJpsPlusTable(m NavigationMap, data []byte) (*JpsPlusTable, error) {
+	t := &JpsPlusTable{}
+	err := unmarshalAny(data, t)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkMapSize(m, int(t.cols), int(t.rows))
+	if err != nil {
+		return nil, err
+	}
+
+	return t, nil
+}
+
+func (t *JpsPlusTable) GetColRow() (col uint32, row uint32) {
+	return t.cols, t.rows
+}
+
+func (t *JpsPlusTable) CanObliqueMove() bool {
+	return t.canObliqueMove
+}
+
+func (t *JpsPlusTable) GetDistance(col int, row int, dir int) int {
+	if col < 0 || row < 0 || col >= int(t.cols) || row >= int(t.rows) {
+		return 0
+	}
+
+	return int(t.distances[t.getIndex(col, row, dir)])
+}
+
+type jpsPlusJson struct {
+	Version        int     `json:"version"`
+	Cols           uint32  `json:"cols"`
+	Rows           uint32  `json:"rows"`
+	CanObliqueMove bool    `json:"canObliqueMove"`
+	Distances      []int16 `json:"distances"`
+	Checksum       uint32  `json:"checksum"`
+}
+
+func (t *JpsPlusTable) MarshalBinary() ([]byte, error) {
+	data := make([]byte, jpsPlusHeaderSize+len(t.distances)*2, jpsPlusHeaderSize+len(t.distances)*2+encodingChecksumSize)
+	copy(data, jpsPlusMagic)
+	data[4] = jpsPlusVersion
+	if t.canObliqueMove {
+		data[5] = 1
+	}
+
+	binary.LittleEndian.PutUint32(data[6:], t.cols)
+	binary.LittleEndian.PutUint32(data[10:], t.rows)
+	for i, dist := range t.distances {
+		binary.LittleEndian.PutUint16(data[jpsPlusHeaderSize+i*2:], uint16(dist))
+	}
+
+	return appendChecksum(data), nil
+}
+
+func (t *JpsPlusTable) UnmarshalBinary(data []byte) error {
+	if len(data) < jpsPlusHeaderSize {
+		return ErrEncodingBadSize
+	}
+
+	if string(data[:4]) != jpsPlusMagic {
+		return ErrEncodingBadMagic
+	}
+
+	switch data[4] {
+	case jpsPlusVersion:
+		body, err := verifyChecksum(data)
+		if err != nil {
+			return err
+		}
+
+		data = body
+	case jpsPlusOldVersion:
+	default:
+		return ErrEncodingBadVersion
+	}
+
+	// the checksum is stripped
+	if len(data) < jpsPlusHeaderSize {
+		return ErrEncodingBadSize
+	}
+
+	// 2 bytes for each direction of each grid, cols * rows never overflows in uint64
+	cols := binary.LittleEndian.Uint32(data[6:])
+	rows := binary.LittleEndian.Uint32(data[10:])
+	bodySize := uint64(len(data) - jpsPlusHeaderSize)
+	gridSize := uint64(len(jpsPlusVectors) * 2)
+	if bodySize%gridSize != 0 || uint64(cols)*uint64(rows) != bodySize/gridSize {
+		return ErrEncodingBadSize
+	}
+
+	count := int(bodySize / 2)
+
+	t.cols = cols
+	t.rows = rows
+	t.canObliqueMove = (data[5] == 1)
+	t.distances = make([]int16, count)
+	for i := range t.distances {
+		t.distances[i] = int16(binary.LittleEndian.Uint16(data[jpsPlusHeaderSize+i*2:]))
+	}
+
+	return nil
+}
+
+func (t *JpsPlusTable) MarshalJSON() ([]byte, error) {
+	data, _ := t.MarshalBinary()
+	return json.Marshal(&jpsPlusJson{
+		Version:        jpsPlusVersion,
+		Cols:           t.cols,
+		Rows:           t.rows,
+		CanObliqueMove: t.canObliqueMove,
+		Distances:      t.distances,
+		Checksum:       getChecksum(data),
+	})
+}
+
+func (t *JpsPlusTable) UnmarshalJSON(data []byte) error {
+	j := &jpsPlusJson{}
+	err := json.Unmarshal(data, j)
+	if err != nil {
+		return err
+	}
+
+	if j.Version != jpsPlusVersion {
+		return ErrEncodingBadVersion
+	}
+
+	if len(j.Distances)%len(jpsPlusVectors) != 0 || uint64(j.Cols)*uint64(j.Rows) != uint64(len(j.Distances)/len(jpsPlusVectors)) {
+		return ErrEncodingBadSize
+	}
+
+	decoded := &JpsPlusTable{cols: j.Cols, rows: j.Rows, canObliqueMove: j.CanObliqueMove, distances: j.Distances}
+	binaryData, _ := decoded.MarshalBinary()
+	if getChecksum(binaryData) != j.Checksum {
+		return ErrEncodingBadChecksum
+	}
+
+	*t = *decoded
+	return nil
+}
+
+func (t *JpsPlusTable) getIndex(col int, row int, dir int) int {
+	return (row*int(t.cols)+col)*len(jpsPlusVectors) + dir
+}
+
+func (t *JpsPlusTable) build(m NavigationMap) {
+	j := NewJps(0, t.canObliqueMove)
+
+	// orthogonal directions first, oblique directions depend on them
+	for dir, vec := range jpsPlusVectors {
+		if !vec.IsOblique() {
+			t.buildDirection(m, j, dir, vec)
+		}
+	}
+
+	for dir, vec := range jpsPlusVectors {
+		if vec.IsOblique() {
+			t.buildDirection(m, j, dir, vec)
+		}
+	}
+}
+
+func (t *JpsPlusTable) buildDirection(m NavigationMap, j *Jps, dir int, vec *Vector) {
+	cols := int(t.cols)
+	rows := int(t.rows)
+
+	// visit the next grid along the direction before the current one
+	for y := 0; y < rows; y++ {
+		row := y
+		if vec.Y > 0 {
+			row = rows - 1 - y
+		}
+
+		for x := 0; x < cols; x++ {
+			col := x
+			if vec.X > 0 {
+				col = cols - 1 - x
+			}
+
+			if !m.CanCross(col, row) {
+				continue
+			}
+
+			t.distances[t.getIndex(col, row, dir)] = int16(t.getDistance(m, j, dir, vec, col, row))
+		}
+	}
+}
+
+func (t *JpsPlusTable) getDistance(m NavigationMap, j *Jps, dir int, vec *Vector, col int, row int) int {
+	nextCol := col + vec.X
+	nextRow := row + vec.Y
+
+	// wall
+	if vec.IsOblique() {
+		if !j.canMoveOblique(m, NewGrid(col, row), nextCol, nextRow) {
+			return 0
+		}
+	} else if _, ok := getEdgeGValue(m, col, row, nextCol, nextRow); !ok {
+		return 0
+	}
+
+	// next grid is a jump point
+	if t.isJumpPoint(m, j, vec, nextCol, nextRow) {
+		return 1
+	}
+
+	nextDist := t.GetDistance(nextCol, nextRow, dir)
+	if nextDist > 0 {
+		if nextDist >= jpsPlusMaxDist {
+			return jpsPlusMaxDist
+		}
+
+		return nextDist + 1
+	}
+
+	// too far away, break the line with a jump point
+	if nextDist <= -jpsPlusMaxDist {
+		return jpsPlusMaxDist
+	}
+
+	return nextDist - 1
+}
+
+func (t *JpsPlusTable) isJumpPoint(m NavigationMap, j *Jps, vec *Vector, col int, row int) bool {
+	if j.isJumpPoint(m, vec, col, row) {
+		return true
+	}
+
+	if !vec.IsOblique() {
+		return false
+	}
+
+	// a straight jump point can be found from the oblique grid
+	for dir, orthogonal := range jpsPlusVectors {
+		if orthogonal.IsOblique() {
+			continue
+		}
+
+		if orthogonal.X != 0 && orthogonal.X != vec.X {
+			continue
+		}
+
+		if orthogonal.Y != 0 && orthogonal.Y != vec.Y {
+			continue
+		}
+
+		if t.GetDistance(col, row, dir) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+//========================
+//      JpsPlus
+//========================
+type JpsPlus struct {
+	*BasePathFinder
+	jps   *Jps
+	table *JpsPlusTable
+}
+
+func NewJpsPlus(table *JpsPlusTable) *JpsPlus {
+	j := &JpsPlus{
+		jps:   NewJps(0, table.CanObliqueMove()),
+		table: table,
+	}
+
+	j.BasePathFinder = NewBasePathFinder(j)
+	if table.CanObliqueMove() {
+		j.SetHeuristic(ChebyshevHeuristic)
+	}
+
+	return j
+}
+
+func (j *JpsPlus) CanObliqueMove() bool {
+	return j.table.CanObliqueMove()
+}
+
+func (j *JpsPlus) GetTable() *JpsPlusTable {
+	return j.table
+}
+
+func (j *JpsPlus) CreateFirstNode(col int, row int) PathNode {
+	return newPooledJpsNode(j.BasePathFinder, nil, VecStart, 0, col, row, true)
+}
+
+func (j *JpsPlus) UnfoldGrid(m NavigationMap, dstGrid *Grid, node PathNode) {
+	grid := node.GetGrid()
+
+	// unfold all directions for the grid with g value changed
+	vecParent := node.GetParentVector()
+	if j.jps.hasGValueChange(m, grid.Col, grid.Row) {
+		vecParent = VecStart
+	}
+
+	for dir, vec := range jpsPlusVectors {
+		// skip the directions going back
+		if vecParent.X*vec.X+vecParent.Y*vec.Y < 0 {
+			continue
+		}
+
+		j.jump(m, dstGrid, node, vec, j.table.GetDistance(grid.Col, grid.Row, dir))
+	}
+
+	j.AddNodeToCloseList(node)
+}
+
+func (j *JpsPlus) jump(m NavigationMap, dstGrid *Grid, parent PathNode, vec *Vector, dist int) {
+	grid := parent.GetGrid()
+	j.addScannedJump(grid, vec, dist)
+	steps := j.getStepsToDst(grid, dstGrid, vec, dist)
+	if steps <= 0 {
+		if dist <= 0 {
+			return
+		}
+
+		steps = dist
+	}
+
+	col := grid.Col + vec.X*steps
+	row := grid.Row + vec.Y*steps
+	minGValue := parent.GetMinGValue() + j.getJumpGValue(m, grid, vec, steps)
+	j.stats.JumpScanLength += steps
+	if j.observer != nil {
+		j.observer.OnJumpPointFound(parent, col, row, minGValue)
+	}
+
+	// already in open list or close list, update min G value
+	if j.UpdateExistList(m, col, row, parent, vec, minGValue) {
+		return
+	}
+
+	node := newPooledJpsNode(j.BasePathFinder, parent, vec, minGValue, col, row, true)
+	j.AddNodeToOpenList(node)
+}
+
+// the grids the distance of the table depends on, the distance of an oblique jump
+// depends on the straight jumps from the grids it passes
+func (j *JpsPlus) addScannedJump(grid *Grid, vec *Vector, dist int) {
+	steps := getJpsPlusReach(dist)
+	if vec.IsOblique() {
+		horizontal := getJpsPlusDir(vec.X, 0)
+		vertical := getJpsPlusDir(0, vec.Y)
+		for i := 1; i <= steps; i++ {
+			col := grid.Col + vec.X*i
+			row := grid.Row + vec.Y*i
+			j.AddScannedGrid(col+vec.X*getJpsPlusReach(j.table.GetDistance(col, row, horizontal)), row)
+			j.AddScannedGrid(col, row+vec.Y*getJpsPlusReach(j.table.GetDistance(col, row, vertical)))
+		}
+	}
+
+	j.AddScannedGrid(grid.Col+vec.X*steps, grid.Row+vec.Y*steps)
+}
+
+// the steps to the jump point, or to the wall
+func getJpsPlusReach(dist int) int {
+	if dist > 0 {
+		return dist
+	}
+
+	return 1 - dist
+}
+
+func getJpsPlusDir(x int, y int) int {
+	for dir, vec := range jpsPlusVectors {
+		if vec.X == x && vec.Y == y {
+			return dir
+		}
+	}
+
+	return -1
+}
+
+// the steps to the dest grid, or the grid in the same row or column with the dest grid
+func (j *JpsPlus) getStepsToDst(grid *Grid, dstGrid *Grid, vec *Vector, dist int) int {
+	dx := dstGrid.Col - grid.Col
+	dy := dstGrid.Row - grid.Row
+	if signInt(dx) != vec.X || signInt(dy) != vec.Y {
+		return 0
+	}
+
+	steps := absInt(dx) + absInt(dy)
+	if vec.IsOblique() {
+		steps = minInt(absInt(dx), absInt(dy))
+	}
+
+	if steps > absInt(dist) {
+		return 0
+	}
+
+	return steps
+}
+
+func (j *JpsPlus) getJumpGValue(m NavigationMap, grid *Grid, vec *Vector, steps int) uint32 {
+	if !vec.IsOblique() {
+		return getLineGValue(m, grid, NewGrid(grid.Col+vec.X*steps, grid.Row+vec.Y*steps))
+	}
+
+	gValue := uint32(0)
+	from := NewGrid(grid.Col, grid.Row)
+	for i := 0; i < steps; i++ {
+		gValue += j.jps.getMinGValueOblique(m, from, from.Col+vec.X, from.Row+vec.Y)
+		from.Update(from.Col+vec.X, from.Row+vec.Y)
+	}
+
+	return gValue
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+
+	return v
+}
@@ -0,0 +1,57 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestJpsPlusTableEncoding(t *testing.T) {
+	m := newTestMap(t, "..#", "...")
+	table := NewJpsPlusTable(m, true)
+	data, _ := table.MarshalBinary()
+	jsonData, _ := json.Marshal(table)
+
+	for _, encoded := range [][]byte{data, jsonData} {
+		loaded, err := LoadJpsPlusTable(m, encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if !loaded.CanObliqueMove() || loaded.GetDistance(0, 0, 2) != table.GetDistance(0, 0, 2) {
+			t.Error("the loaded table differs")
+		}
+	}
+
+	corrupt := func(i int, b byte) []byte {
+		corrupted := append([]byte(nil), data...)
+		corrupted[i] = b
+		return corrupted
+	}
+
+	cases := []struct {
+		name string
+		m    NavigationMap
+		data []byte
+		want error
+	}{
+		{"magic", m, corrupt(0, 'X'), ErrEncodingBadMagic},
+		{"version", m, corrupt(4, 9), ErrEncodingBadVersion},
+		{"size", m, data[:jpsPlusHeaderSize-1], ErrEncodingBadSize},
+		{"checksum", m, corrupt(jpsPlusHeaderSize, 0x7f), ErrEncodingBadChecksum},
+		{"map", newTestMap(t, "...", "...", "..."), data, ErrEncodingMapMismatch},
+		// the checksums are valid, the headers are not
+		{"truncated", m, appendChecksum(append([]byte(nil), data[:jpsPlusHeaderSize-encodingChecksumSize]...)), ErrEncodingBadSize},
+		{"huge size", m, appendChecksum(append(append([]byte(nil), data[:6]...), 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff)), ErrEncodingBadSize},
+		{"odd size", m, appendChecksum(append([]byte(nil), data[:len(data)-encodingChecksumSize-1]...)), ErrEncodingBadSize},
+	}
+
+	for _, c := range cases {
+		if _, err := LoadJpsPlusTable(c.m, c.data); err != c.want {
+			t.Errorf("%s: got error %v, want %v", c.name, err, c.want)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// an oblique move through a side grid costs the same as two orthogonal moves,
+// so jps without direct oblique moves has the same g values as 4 directions
+func TestJpsWeightedDijkstra(t *testing.T) {
+	cases := []struct {
+		name           string
+		canObliqueMove bool
+		vectors        []*Vector
+		maxDeep        uint32
+	}{
+		{"jps", false, orthogonalVectors, 0},
+		{"jps deep 4", false, orthogonalVectors, 4},
+		{"jps8", true, ringVectors, 0},
+	}
+
+	for _, c := range cases {
+		finder := NewJps(c.maxDeep, c.canObliqueMove)
+		for seed := int64(0); seed < 40; seed++ {
+			m := newRandomTestMap(seed, 24, 24, 0.25, 4)
+			rnd := rand.New(rand.NewSource(seed))
+			for i := 0; i < 8; i++ {
+				startGrid := NewGrid(rnd.Intn(24), rnd.Intn(24))
+				dstGrid := NewGrid(rnd.Intn(24), rnd.Intn(24))
+				if !m.CanCross(startGrid.Col, startGrid.Row) || !m.CanCross(dstGrid.Col, dstGrid.Row) {
+					continue
+				}
+
+				want, bReachable := getDijkstraGValues(m, startGrid, c.vectors, false)[*dstGrid]
+				gValue, ok := getPathGValue(t, finder, m, startGrid, dstGrid)
+				if ok != bReachable || gValue != want {
+					t.Errorf("%s seed %d %v -> %v: got g value %d (%v), want %d (%v)",
+						c.name, seed, startGrid, dstGrid, gValue, ok, want, bReachable)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,416 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "math"
+
+//========================
+//  LayeredNavigationMap
+//========================
+// a navigation map with multiple floors or a voxel volume
+type LayeredNavigationMap interface {
+	GetColRowLayer() (col uint32, row uint32, layer uint32)
+	CanCross(col int, row int, layer int) bool
+	GetGValue(col int, row int, layer int) uint32
+	GetMinGValue() uint32
+	// the explicit moves between layers, such as stairs and elevators
+	GetConnectors() []*Connector
+}
+
+// a one-way move from a grid to a grid of any layer
+type Connector struct {
+	From     Grid3
+	To       Grid3
+	GValue   uint32
+	LinkType LinkType
+}
+
+//========================
+//      LayeredMap
+//========================
+// a multi-floor map made of the navigation maps of the same size
+type LayeredMap struct {
+	floors     []NavigationMap
+	connectors []*Connector
+}
+
+func NewLayeredMap(floors []NavigationMap) *LayeredMap {
+	return &LayeredMap{
+		floors:     floors,
+		connectors: make([]*Connector, 0),
+	}
+}
+
+func (m *LayeredMap) GetFloor(layer int) (NavigationMap, bool) {
+	if layer < 0 || layer >= len(m.floors) {
+		return nil, false
+	}
+
+	return m.floors[layer], true
+}
+
+// add a connector, and the reverse one if it is two way
+func (m *LayeredMap) AddConnector(from *Grid3, to *Grid3, gValue uint32, linkType LinkType, bTwoWay bool) {
+	m.connectors = append(m.connectors, &Connector{From: *from, To: *to, GValue: gValue, LinkType: linkType})
+	if bTwoWay {
+		m.connectors = append(m.connectors, &Connector{From: *to, To: *from, GValue: gValue, LinkType: linkType})
+	}
+}
+
+// remove all the connectors between two grids
+func (m *LayeredMap) RemoveConnector(from *Grid3, to *Grid3) {
+	connectors := m.connectors[:0]
+	for _, connector := range m.connectors {
+		if (connector.From.IsSameGrid(from) && connector.To.IsSameGrid(to)) || (connector.From.IsSameGrid(to) && connector.To.IsSameGrid(from)) {
+			continue
+		}
+
+		connectors = append(connectors, connector)
+	}
+
+	m.connectors = connectors
+}
+
+func (m *LayeredMap) GetConnectors() []*Connector {
+	return m.connectors
+}
+
+func (m *LayeredMap) GetColRowLayer() (col uint32, row uint32, layer uint32) {
+	if len(m.floors) == 0 {
+		return 0, 0, 0
+	}
+
+	col, row = m.floors[0].GetColRow()
+	return col, row, uint32(len(m.floors))
+}
+
+func (m *LayeredMap) CanCross(col int, row int, layer int) bool {
+	floor, ok := m.GetFloor(layer)
+	if !ok {
+		return false
+	}
+
+	return floor.CanCross(col, row)
+}
+
+func (m *LayeredMap) GetGValue(col int, row int, layer int) uint32 {
+	floor, ok := m.GetFloor(layer)
+	if !ok {
+		return 0
+	}
+
+	return floor.GetGValue(col, row)
+}
+
+func (m *LayeredMap) GetMinGValue() uint32 {
+	minGValue := uint32(math.MaxUint32)
+	for _, floor := range m.floors {
+		if g := floor.GetMinGValue(); g < minGValue {
+			minGValue = g
+		}
+	}
+
+	if minGValue == math.MaxUint32 {
+		return 0
+	}
+
+	return minGValue
+}
+
+//========================
+//     Neighbourhood
+//========================
+type Neighbourhood int
+
+const (
+	// move to the 4 orthogonal neighbours of the same layer, change layer by the connectors only
+	NeighbourhoodLayer Neighbourhood = iota
+	// voxel move through the 6 faces
+	Neighbourhood6
+	// voxel move through the faces and the 12 edges, the edges are passed through the side grids
+	Neighbourhood18
+	// voxel move through the faces, the edges and the 8 corners, without cutting the corners
+	Neighbourhood26
+)
+
+// the max count of the axes changed by one move
+func (n Neighbourhood) getMaxAxes() int {
+	switch n {
+	case Neighbourhood18:
+		return 2
+	case Neighbourhood26:
+		return 3
+	default:
+		return 1
+	}
+}
+
+//========================
+//    LayeredPathNode
+//========================
+type LayeredPathNode struct {
+	Grid   Grid3
+	GValue uint32
+	// the connector used to move to the grid, nil for a normal move
+	Connector *Connector
+}
+
+//========================
+//      LayeredAStar
+//========================
+// find path on a layered map, the layers are stacked into one navigation map
+// so the AStar runs unchanged
+type LayeredAStar struct {
+	astar         *AStar
+	neighbourhood Neighbourhood
+}
+
+func NewLayeredAStar(neighbourhood Neighbourhood) *LayeredAStar {
+	return &LayeredAStar{
+		astar:         NewAStar(),
+		neighbourhood: neighbourhood,
+	}
+}
+
+func (a *LayeredAStar) GetNeighbourhood() Neighbourhood {
+	return a.neighbourhood
+}
+
+func (a *LayeredAStar) Reset() {
+	a.astar.Reset()
+}
+
+func (a *LayeredAStar) FindPath(m LayeredNavigationMap, startGrid *Grid3, dstGrid *Grid3) ([]*LayeredPathNode, bool) {
+	stackMap := newStackedLayerMap(m, a.neighbourhood)
+	if !stackMap.isInMap(startGrid.Col, startGrid.Row, startGrid.Layer) || !stackMap.isInMap(dstGrid.Col, dstGrid.Row, dstGrid.Layer) {
+		return nil, false
+	}
+
+	fullPath, ok := a.astar.FindPath(stackMap, stackMap.toGrid(startGrid), stackMap.toGrid(dstGrid))
+	if !ok {
+		return nil, false
+	}
+
+	path := make([]*LayeredPathNode, 0, len(fullPath))
+	for _, node := range fullPath {
+		grid := node.GetGrid()
+		pathNode := &LayeredPathNode{
+			Grid:   stackMap.toGrid3(grid.Col, grid.Row),
+			GValue: node.GetMinGValue(),
+		}
+
+		if link := GetNodeLink(node); link != nil {
+			pathNode.Connector = stackMap.connectors[link]
+		}
+
+		path = append(path, pathNode)
+	}
+
+	return path, true
+}
+
+//========================
+//    stackedLayerMap
+//========================
+// the layers are stacked by rows, the row of a grid is row + layer * rows
+type stackedLayerMap struct {
+	m             LayeredNavigationMap
+	neighbourhood Neighbourhood
+	cols          int
+	rows          int
+	layers        int
+	froms         map[Grid][]*Link
+	connectors    map[*Link]*Connector
+}
+
+func newStackedLayerMap(m LayeredNavigationMap, neighbourhood Neighbourhood) *stackedLayerMap {
+	cols, rows, layers := m.GetColRowLayer()
+	s := &stackedLayerMap{
+		m:             m,
+		neighbourhood: neighbourhood,
+		cols:          int(cols),
+		rows:          int(rows),
+		layers:        int(layers),
+		froms:         make(map[Grid][]*Link),
+		connectors:    make(map[*Link]*Connector),
+	}
+
+	for _, connector := range m.GetConnectors() {
+		if !s.isInMap(connector.From.Col, connector.From.Row, connector.From.Layer) || !s.isInMap(connector.To.Col, connector.To.Row, connector.To.Layer) {
+			continue
+		}
+
+		link := &Link{
+			From:     *s.toGrid(&connector.From),
+			To:       *s.toGrid(&connector.To),
+			GValue:   connector.GValue,
+			LinkType: connector.LinkType,
+		}
+
+		s.froms[link.From] = append(s.froms[link.From], link)
+		s.connectors[link] = connector
+	}
+
+	return s
+}
+
+func (s *stackedLayerMap) GetColRow() (col uint32, row uint32) {
+	return uint32(s.cols), uint32(s.rows * s.layers)
+}
+
+func (s *stackedLayerMap) CanCross(col int, row int) bool {
+	grid := s.toGrid3(col, row)
+	if !s.isInMap(grid.Col, grid.Row, grid.Layer) {
+		return false
+	}
+
+	return s.m.CanCross(grid.Col, grid.Row, grid.Layer)
+}
+
+func (s *stackedLayerMap) GetGValue(col int, row int) uint32 {
+	grid := s.toGrid3(col, row)
+	return s.m.GetGValue(grid.Col, grid.Row, grid.Layer)
+}
+
+// the connectors are taken into account by GetHValue, not by the base g value
+func (s *stackedLayerMap) GetMinGValue() uint32 {
+	return s.m.GetMinGValue()
+}
+
+// a move changes more than one axis goes through the side grids without cutting the corners,
+// like the oblique moves of jps, it costs the g value of the dest grid
+func (s *stackedLayerMap) GetEdgeGValue(fromCol int, fromRow int, toCol int, toRow int) (uint32, bool) {
+	if !s.CanCross(toCol, toRow) {
+		return 0, false
+	}
+
+	from := s.toGrid3(fromCol, fromRow)
+	to := s.toGrid3(toCol, toRow)
+	if !s.canMoveBySide(&from, &to) {
+		return 0, false
+	}
+
+	return s.GetGValue(toCol, toRow), true
+}
+
+func (s *stackedLayerMap) HasEdgeCost(col int, row int) bool {
+	return s.neighbourhood.getMaxAxes() > 1
+}
+
+// whether the grids can be connected by the orthogonal moves through the crossable side grids
+func (s *stackedLayerMap) canMoveBySide(from *Grid3, to *Grid3) bool {
+	steps := []*Grid3{
+		NewGrid3(from.Col+signInt(to.Col-from.Col), from.Row, from.Layer),
+		NewGrid3(from.Col, from.Row+signInt(to.Row-from.Row), from.Layer),
+		NewGrid3(from.Col, from.Row, from.Layer+signInt(to.Layer-from.Layer)),
+	}
+
+	for _, side := range steps {
+		if side.IsSameGrid(from) {
+			continue
+		}
+
+		// the last orthogonal move
+		if side.IsSameGrid(to) {
+			return true
+		}
+
+		if s.isInMap(side.Col, side.Row, side.Layer) && s.m.CanCross(side.Col, side.Row, side.Layer) && s.canMoveBySide(side, to) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// the neighbours never cross the border of a layer
+func (s *stackedLayerMap) GetNeighbours(col int, row int) []*Grid {
+	grid := s.toGrid3(col, row)
+	maxAxes := s.neighbourhood.getMaxAxes()
+	minLayer := grid.Layer
+	maxLayer := grid.Layer
+	if s.neighbourhood != NeighbourhoodLayer {
+		minLayer--
+		maxLayer++
+	}
+
+	neighbours := make([]*Grid, 0)
+	for layer := minLayer; layer <= maxLayer; layer++ {
+		for nextRow := grid.Row - 1; nextRow <= grid.Row+1; nextRow++ {
+			for nextCol := grid.Col - 1; nextCol <= grid.Col+1; nextCol++ {
+				axes := absInt(nextCol-grid.Col) + absInt(nextRow-grid.Row) + absInt(layer-grid.Layer)
+				if axes == 0 || axes > maxAxes || !s.isInMap(nextCol, nextRow, layer) {
+					continue
+				}
+
+				neighbours = append(neighbours, s.toGrid(NewGrid3(nextCol, nextRow, layer)))
+			}
+		}
+	}
+
+	return neighbours
+}
+
+func (s *stackedLayerMap) GetLinks(col int, row int) []*Link {
+	return s.froms[Grid{Col: col, Row: row}]
+}
+
+// the heuristic of the finder doesn't know the layers, use the distance of the neighbourhood instead,
+// and take the connectors into account like the link layer
+func (s *stackedLayerMap) GetHValue(grid *Grid, dstGrid *Grid, baseGValue uint32, heuristic HeuristicFunc) uint32 {
+	from := s.toGrid3(grid.Col, grid.Row)
+	to := s.toGrid3(dstGrid.Col, dstGrid.Row)
+	hValue := uint64(s.getDistance(&from, &to)) * uint64(baseGValue)
+	if len(s.connectors) == 0 {
+		return uint32(hValue)
+	}
+
+	minFrom := uint64(math.MaxUint32)
+	minTo := uint64(math.MaxUint32)
+	minLink := uint64(math.MaxUint32)
+	for _, connector := range s.connectors {
+		minFrom = minUint64(minFrom, uint64(s.getDistance(&from, &connector.From))*uint64(baseGValue))
+		minTo = minUint64(minTo, uint64(s.getDistance(&connector.To, &to))*uint64(baseGValue))
+		minLink = minUint64(minLink, uint64(connector.GValue))
+	}
+
+	return uint32(minUint64(hValue, minFrom+minLink+minTo))
+}
+
+// the least moves between two grids
+func (s *stackedLayerMap) getDistance(grid *Grid3, dstGrid *Grid3) uint32 {
+	dCol := absInt(dstGrid.Col - grid.Col)
+	dRow := absInt(dstGrid.Row - grid.Row)
+	dLayer := absInt(dstGrid.Layer - grid.Layer)
+	switch s.neighbourhood {
+	case NeighbourhoodLayer:
+		// the layer only changes by the connectors
+		return uint32(dCol + dRow)
+	case Neighbourhood6:
+		return uint32(dCol + dRow + dLayer)
+	case Neighbourhood18:
+		// each move changes 2 axes at most
+		maxAxis := maxInt(dCol, maxInt(dRow, dLayer))
+		return uint32(maxInt(maxAxis, (dCol+dRow+dLayer+1)/2))
+	default:
+		return uint32(maxInt(dCol, maxInt(dRow, dLayer)))
+	}
+}
+
+func (s *stackedLayerMap) isInMap(col int, row int, layer int) bool {
+	return col >= 0 && row >= 0 && layer >= 0 && col < s.cols && row < s.rows && layer < s.layers
+}
+
+func (s *stackedLayerMap) toGrid(grid *Grid3) *Grid {
+	return NewGrid(grid.Col, grid.Row+grid.Layer*s.rows)
+}
+
+func (s *stackedLayerMap) toGrid3(col int, row int) Grid3 {
+	if s.rows <= 0 || row < 0 {
+		return Grid3{Col: col, Row: row, Layer: -1}
+	}
+
+	return Grid3{Col: col, Row: row % s.rows, Layer: row / s.rows}
+}
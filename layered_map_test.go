@@ -0,0 +1,105 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+func getLayeredPathGValue(m LayeredNavigationMap, neighbourhood Neighbourhood, startGrid *Grid3, dstGrid *Grid3) (uint32, bool) {
+	path, ok := NewLayeredAStar(neighbourhood).FindPath(m, startGrid, dstGrid)
+	if !ok {
+		return 0, false
+	}
+
+	return path[len(path)-1].GValue, true
+}
+
+func TestLayeredMapConnectors(t *testing.T) {
+	m := NewLayeredMap([]NavigationMap{NewGridMap(5, 2, 10), NewGridMap(5, 2, 10)})
+	m.AddConnector(NewGrid3(4, 0, 0), NewGrid3(4, 0, 1), 1, LinkTypeTeleport, false)
+
+	path, ok := NewLayeredAStar(NeighbourhoodLayer).FindPath(m, NewGrid3(0, 0, 0), NewGrid3(0, 0, 1))
+	if !ok || path[len(path)-1].GValue != 81 {
+		t.Fatalf("got path %v (%v), want g value 81", path, ok)
+	}
+
+	bConnector := false
+	for _, node := range path {
+		bConnector = bConnector || node.Connector != nil
+	}
+
+	if !bConnector {
+		t.Error("the connector is not in the path")
+	}
+
+	if _, ok := getLayeredPathGValue(m, NeighbourhoodLayer, NewGrid3(0, 0, 1), NewGrid3(0, 0, 0)); ok {
+		t.Error("path found back through the one-way connector")
+	}
+
+	// the cheap connector is charged by the heuristic, not by the base g value
+	s := newStackedLayerMap(m, NeighbourhoodLayer)
+	if s.GetMinGValue() != 10 {
+		t.Errorf("got min g value %d, want 10", s.GetMinGValue())
+	}
+
+	for col := 0; col < 5; col++ {
+		from := s.toGrid(NewGrid3(col, 1, 0))
+		gValue, _ := getLayeredPathGValue(m, NeighbourhoodLayer, NewGrid3(col, 1, 0), NewGrid3(0, 0, 1))
+		if hValue := s.GetHValue(from, s.toGrid(NewGrid3(0, 0, 1)), s.GetMinGValue(), ManhattanHeuristic); hValue > gValue {
+			t.Errorf("(%d, 1, 0): got h value %d, want at most %d", col, hValue, gValue)
+		}
+	}
+}
+
+func TestLayeredMapVoxelNeighbours(t *testing.T) {
+	m := NewLayeredMap([]NavigationMap{NewGridMap(3, 3, 1), NewGridMap(3, 3, 1), NewGridMap(3, 3, 1)})
+	cases := []struct {
+		neighbourhood Neighbourhood
+		count         int
+		gValue        uint32
+	}{
+		{NeighbourhoodLayer, 4, 0},
+		{Neighbourhood6, 6, 6},
+		{Neighbourhood18, 18, 3},
+		{Neighbourhood26, 26, 2},
+	}
+
+	for _, c := range cases {
+		s := newStackedLayerMap(m, c.neighbourhood)
+		center := s.toGrid(NewGrid3(1, 1, 1))
+		if neighbours := s.GetNeighbours(center.Col, center.Row); len(neighbours) != c.count {
+			t.Errorf("neighbourhood %d: got %d neighbours, want %d", c.neighbourhood, len(neighbours), c.count)
+		}
+
+		gValue, ok := getLayeredPathGValue(m, c.neighbourhood, NewGrid3(0, 0, 0), NewGrid3(2, 2, 2))
+		if ok != (c.gValue != 0) || gValue != c.gValue {
+			t.Errorf("neighbourhood %d: got g value %d (%v), want %d", c.neighbourhood, gValue, ok, c.gValue)
+		}
+	}
+}
+
+// the diagonal moves don't squeeze between the walls
+func TestLayeredMapVoxelCorners(t *testing.T) {
+	walled := NewLayeredMap([]NavigationMap{newTestMap(t, ".#", "#.")})
+	if _, ok := getLayeredPathGValue(walled, Neighbourhood18, NewGrid3(0, 0, 0), NewGrid3(1, 1, 0)); ok {
+		t.Error("18: path found through the corner")
+	}
+
+	side := NewLayeredMap([]NavigationMap{newTestMap(t, "..", "#.")})
+	if gValue, ok := getLayeredPathGValue(side, Neighbourhood18, NewGrid3(0, 0, 0), NewGrid3(1, 1, 0)); !ok || gValue != 1 {
+		t.Errorf("18: got g value %d (%v), want 1", gValue, ok)
+	}
+
+	// only the corners of the cube are open
+	cube := NewLayeredMap([]NavigationMap{newTestMap(t, ".#", "##"), newTestMap(t, "##", "#.")})
+	if _, ok := getLayeredPathGValue(cube, Neighbourhood26, NewGrid3(0, 0, 0), NewGrid3(1, 1, 1)); ok {
+		t.Error("26: path found through the corner")
+	}
+
+	// the side grids (1, 0, 0) and (1, 1, 0) lead to the corner
+	cube = NewLayeredMap([]NavigationMap{newTestMap(t, "..", "#."), newTestMap(t, "##", "#.")})
+	if gValue, ok := getLayeredPathGValue(cube, Neighbourhood26, NewGrid3(0, 0, 0), NewGrid3(1, 1, 1)); !ok || gValue != 1 {
+		t.Errorf("26: got g value %d (%v), want 1", gValue, ok)
+	}
+}
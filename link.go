@@ -0,0 +1,265 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "math"
+
+//========================
+//      Link
+//========================
+// the kind of a link, so the movement can play the right animation
+type LinkType int
+
+const (
+	LinkTypeTeleport LinkType = iota
+	LinkTypeStairs
+	LinkTypeLadder
+	LinkTypeElevator
+	LinkTypeUserBegin
+)
+
+// an extra one-way move from a grid to any other grid
+type Link struct {
+	From     Grid
+	To       Grid
+	GValue   uint32
+	LinkType LinkType
+}
+
+//========================
+//      LinkMap
+//========================
+// optional for a navigation map, the links start from a grid
+type LinkMap interface {
+	NavigationMap
+	GetLinks(col int, row int) []*Link
+}
+
+func getLinks(m NavigationMap, col int, row int) []*Link {
+	linkMap, ok := m.(LinkMap)
+	if !ok {
+		return nil
+	}
+
+	return linkMap.GetLinks(col, row)
+}
+
+//========================
+//      LinkLayer
+//========================
+// add portals, teleporters, stairs or ladders to a navigation map
+type LinkLayer struct {
+	NavigationMap
+	mapForwarder
+	links   []*Link
+	froms   map[Grid][]*Link
+	fromIdx *linkGridIndex
+	toIdx   *linkGridIndex
+	minLink uint32
+}
+
+func NewLinkLayer(m NavigationMap) *LinkLayer {
+	return &LinkLayer{
+		NavigationMap: m,
+		mapForwarder:  mapForwarder{inner: m},
+		links:         make([]*Link, 0),
+		froms:         make(map[Grid][]*Link),
+		fromIdx:       newLinkGridIndex(nil),
+		toIdx:         newLinkGridIndex(nil),
+	}
+}
+
+// add a link, and the reverse one if it is two way
+func (l *LinkLayer) AddLink(from *Grid, to *Grid, gValue uint32, linkType LinkType, bTwoWay bool) {
+	l.addLink(&Link{From: *from, To: *to, GValue: gValue, LinkType: linkType})
+	if bTwoWay {
+		l.addLink(&Link{From: *to, To: *from, GValue: gValue, LinkType: linkType})
+	}
+}
+
+// remove all the links between two grids
+func (l *LinkLayer) RemoveLink(from *Grid, to *Grid) {
+	links := l.links[:0]
+	for _, link := range l.links {
+		if (link.From.IsSameGrid(from) && link.To.IsSameGrid(to)) || (link.From.IsSameGrid(to) && link.To.IsSameGrid(from)) {
+			continue
+		}
+
+		links = append(links, link)
+	}
+
+	l.links = links
+	l.froms = make(map[Grid][]*Link)
+	for _, link := range l.links {
+		l.froms[link.From] = append(l.froms[link.From], link)
+	}
+
+	l.buildIndexes()
+}
+
+// the links of the layer and the ones of the inner map
+func (l *LinkLayer) GetLinks(col int, row int) []*Link {
+	links := l.froms[Grid{Col: col, Row: row}]
+	innerLinks := getLinks(l.NavigationMap, col, row)
+	if len(innerLinks) == 0 {
+		return links
+	}
+
+	if len(links) == 0 {
+		return innerLinks
+	}
+
+	allLinks := make([]*Link, 0, len(links)+len(innerLinks))
+	allLinks = append(allLinks, links...)
+	return append(allLinks, innerLinks...)
+}
+
+func (l *LinkLayer) GetAllLinks() []*Link {
+	return l.links
+}
+
+// a path either goes to the dest grid directly, or goes to the nearest link and
+// leaves from the link nearest to the dest grid, take the smaller one. the nearest
+// links are searched in the buckets around, so the h value of the inner map must
+// not fall as the cols and the rows between the grids grow, as the heuristics do
+func (l *LinkLayer) GetHValue(grid *Grid, dstGrid *Grid, baseGValue uint32, heuristic HeuristicFunc) uint32 {
+	hValue := getHValue(l.NavigationMap, grid, dstGrid, baseGValue, heuristic)
+	if len(l.links) == 0 {
+		return hValue
+	}
+
+	minFrom := l.fromIdx.getMinHValue(grid, func(from *Grid) uint32 {
+		return getHValue(l.NavigationMap, grid, from, baseGValue, heuristic)
+	})
+
+	minTo := l.toIdx.getMinHValue(dstGrid, func(to *Grid) uint32 {
+		return getHValue(l.NavigationMap, to, dstGrid, baseGValue, heuristic)
+	})
+
+	return uint32(minUint64(uint64(hValue), uint64(minFrom)+uint64(l.minLink)+uint64(minTo)))
+}
+
+func (l *LinkLayer) addLink(link *Link) {
+	l.links = append(l.links, link)
+	l.froms[link.From] = append(l.froms[link.From], link)
+	l.buildIndexes()
+}
+
+// the indexes are built again instead of changed, so the searches running on the
+// old ones are not disturbed
+func (l *LinkLayer) buildIndexes() {
+	froms := make([]Grid, 0, len(l.links))
+	tos := make([]Grid, 0, len(l.links))
+	l.minLink = math.MaxUint32
+	for _, link := range l.links {
+		froms = append(froms, link.From)
+		tos = append(tos, link.To)
+		if link.GValue < l.minLink {
+			l.minLink = link.GValue
+		}
+	}
+
+	l.fromIdx = newLinkGridIndex(froms)
+	l.toIdx = newLinkGridIndex(tos)
+}
+
+func minUint64(a uint64, b uint64) uint64 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+func minUint32(a uint32, b uint32) uint32 {
+	if a < b {
+		return a
+	}
+
+	return b
+}
+
+//========================
+//      linkGridIndex
+//========================
+const linkBucketSize = 16
+
+// the grids of the links in the buckets of linkBucketSize x linkBucketSize grids,
+// the nearest one to a grid is searched ring by ring of the buckets around it
+type linkGridIndex struct {
+	buckets   map[Grid][]Grid
+	minBucket Grid
+	maxBucket Grid
+}
+
+func newLinkGridIndex(grids []Grid) *linkGridIndex {
+	idx := &linkGridIndex{
+		buckets: make(map[Grid][]Grid),
+	}
+
+	added := make(map[Grid]bool)
+	for i, grid := range grids {
+		if added[grid] {
+			continue
+		}
+
+		added[grid] = true
+		bucket := getLinkBucket(&grid)
+		idx.buckets[bucket] = append(idx.buckets[bucket], grid)
+		if i == 0 {
+			idx.minBucket = bucket
+			idx.maxBucket = bucket
+			continue
+		}
+
+		idx.minBucket = Grid{Col: minInt(idx.minBucket.Col, bucket.Col), Row: minInt(idx.minBucket.Row, bucket.Row)}
+		idx.maxBucket = Grid{Col: maxInt(idx.maxBucket.Col, bucket.Col), Row: maxInt(idx.maxBucket.Row, bucket.Row)}
+	}
+
+	return idx
+}
+
+// the min h value of the grids in the index, getHValue is the h value between a grid
+// and the fixed one
+func (idx *linkGridIndex) getMinHValue(grid *Grid, getHValue func(linkGrid *Grid) uint32) uint32 {
+	minHValue := uint32(math.MaxUint32)
+	center := getLinkBucket(grid)
+	maxRing := maxInt(maxInt(absInt(center.Col-idx.minBucket.Col), absInt(center.Col-idx.maxBucket.Col)),
+		maxInt(absInt(center.Row-idx.minBucket.Row), absInt(center.Row-idx.maxBucket.Row)))
+	for ring := 0; ring <= maxRing; ring++ {
+		// the grids of the ring are at least dist cols or rows away
+		if ring > 1 && idx.getRingHValue(grid, (ring-1)*linkBucketSize+1, getHValue) >= minHValue {
+			break
+		}
+
+		for row := center.Row - ring; row <= center.Row+ring; row++ {
+			step := 1
+			if row != center.Row-ring && row != center.Row+ring {
+				step = 2 * ring
+			}
+
+			for col := center.Col - ring; col <= center.Col+ring; col += step {
+				bucket := idx.buckets[Grid{Col: col, Row: row}]
+				for i := range bucket {
+					minHValue = minUint32(minHValue, getHValue(&bucket[i]))
+				}
+			}
+		}
+	}
+
+	return minHValue
+}
+
+// the min h value of the grids dist cols or rows away from the grid
+func (idx *linkGridIndex) getRingHValue(grid *Grid, dist int, getHValue func(linkGrid *Grid) uint32) uint32 {
+	hValue := getHValue(NewGrid(grid.Col-dist, grid.Row))
+	hValue = minUint32(hValue, getHValue(NewGrid(grid.Col+dist, grid.Row)))
+	hValue = minUint32(hValue, getHValue(NewGrid(grid.Col, grid.Row-dist)))
+	return minUint32(hValue, getHValue(NewGrid(grid.Col, grid.Row+dist)))
+}
+
+func getLinkBucket(grid *Grid) Grid {
+	return Grid{Col: floorDiv(grid.Col, linkBucketSize), Row: floorDiv(grid.Row, linkBucketSize)}
+}
@@ -0,0 +1,80 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// the h value through the links, checked link by link
+func getTestLinkHValue(l *LinkLayer, grid *Grid, dstGrid *Grid, baseGValue uint32, heuristic HeuristicFunc) uint32 {
+	minFrom := uint64(math.MaxUint32)
+	minTo := uint64(math.MaxUint32)
+	minLink := uint64(math.MaxUint32)
+	for _, link := range l.GetAllLinks() {
+		from := link.From
+		to := link.To
+		minFrom = minUint64(minFrom, uint64(heuristic(grid, &from, baseGValue)))
+		minTo = minUint64(minTo, uint64(heuristic(&to, dstGrid, baseGValue)))
+		minLink = minUint64(minLink, uint64(link.GValue))
+	}
+
+	return uint32(minUint64(uint64(heuristic(grid, dstGrid, baseGValue)), minFrom+minLink+minTo))
+}
+
+func TestLinkLayerHValue(t *testing.T) {
+	heuristics := map[string]HeuristicFunc{
+		"manhattan": ManhattanHeuristic,
+		"chebyshev": ChebyshevHeuristic,
+		"hex":       NewHexLayout(HexPointy, HexOffsetOdd).Heuristic,
+	}
+
+	rnd := rand.New(rand.NewSource(34))
+	l := NewLinkLayer(NewGridMap(200, 200, 1))
+	for i := 0; i < 40; i++ {
+		from := NewGrid(rnd.Intn(200), rnd.Intn(200))
+		to := NewGrid(rnd.Intn(200), rnd.Intn(200))
+		l.AddLink(from, to, uint32(rnd.Intn(5)), LinkTypeTeleport, rnd.Intn(2) == 0)
+		if i%4 == 0 {
+			l.RemoveLink(from, to)
+		}
+
+		for name, heuristic := range heuristics {
+			for j := 0; j < 50; j++ {
+				grid := NewGrid(rnd.Intn(200), rnd.Intn(200))
+				dstGrid := NewGrid(rnd.Intn(200), rnd.Intn(200))
+				got := l.GetHValue(grid, dstGrid, 3, heuristic)
+				if want := getTestLinkHValue(l, grid, dstGrid, 3, heuristic); got != want {
+					t.Fatalf("%s: got h value %d from %v to %v, want %d", name, got, grid, dstGrid, want)
+				}
+			}
+		}
+	}
+}
+
+// the far buckets are not searched
+func TestLinkLayerHValueBuckets(t *testing.T) {
+	l := NewLinkLayer(NewGridMap(1000, 1000, 1))
+	for col := 0; col < 1000; col += 10 {
+		l.AddLink(NewGrid(col, 999), NewGrid(col, 0), 1, LinkTypeTeleport, false)
+	}
+
+	l.AddLink(NewGrid(500, 500), NewGrid(600, 600), 1, LinkTypeTeleport, false)
+	count := 0
+	hValue := l.GetHValue(NewGrid(502, 500), NewGrid(600, 601), 1, func(grid *Grid, dstGrid *Grid, baseGValue uint32) uint32 {
+		count++
+		return ManhattanHeuristic(grid, dstGrid, baseGValue)
+	})
+
+	if hValue != 4 {
+		t.Errorf("got h value %d, want 4", hValue)
+	}
+
+	if count > 20 {
+		t.Errorf("got %d h values for %d links", count, len(l.GetAllLinks()))
+	}
+}
@@ -0,0 +1,252 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrMapEmpty     = errors.New("map loader: empty map")
+	ErrMapBadHeader = errors.New("map loader: bad header")
+)
+
+//========================
+//       Ascii Map
+//========================
+// one line for a row, '.' or ' ' can be crossed with g value 1, '1' - '9' can be
+// crossed with the g value, other chars can't be crossed, the short lines are
+// padded with the grids can't be crossed
+func LoadAsciiMap(r io.Reader) (*GridMap, error) {
+	lines := make([]string, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, strings.TrimRight(scanner.Text(), "\r"))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	// drop the empty lines at the end
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	return newGridMapFromLines(lines, asciiGridRule)
+}
+
+func asciiGridRule(c byte) (uint32, bool) {
+	if c == '.' || c == ' ' {
+		return 1, true
+	}
+
+	if c >= '1' && c <= '9' {
+		return uint32(c - '0'), true
+	}
+
+	return 0, false
+}
+
+//========================
+//      MovingAI Map
+//========================
+// the map format of the moving ai benchmarks, '.', 'G' and 'S' can be crossed, each
+// line of the map should have the width of the header
+func LoadMovingAIMap(r io.Reader) (*GridMap, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	cols := -1
+	rows := -1
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+
+		if fields[0] == "map" {
+			break
+		}
+
+		// the type line is ignored, the map is octile
+		if len(fields) != 2 || (fields[0] != "height" && fields[0] != "width") {
+			continue
+		}
+
+		value, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, ErrMapBadHeader
+		}
+
+		if fields[0] == "height" {
+			rows = value
+		} else {
+			cols = value
+		}
+	}
+
+	if cols < 0 || rows < 0 {
+		return nil, ErrMapBadHeader
+	}
+
+	// the header is not trusted to allocate the lines
+	lines := make([]string, 0)
+	for len(lines) < rows && scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(line) != cols {
+			return nil, ErrMapBadHeader
+		}
+
+		lines = append(lines, line)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(lines) < rows {
+		return nil, fmt.Errorf("map loader: %d rows expected, got %d", rows, len(lines))
+	}
+
+	return newGridMapFromLines(lines, movingAIGridRule)
+}
+
+func movingAIGridRule(c byte) (uint32, bool) {
+	switch c {
+	case '.', 'G', 'S':
+		return 1, true
+	default:
+		return 0, false
+	}
+}
+
+// a query of the moving ai scenario file
+type MovingAIScenario struct {
+	Bucket        int
+	MapName       string
+	Cols          int
+	Rows          int
+	StartGrid     Grid
+	DstGrid       Grid
+	OptimalLength float64
+}
+
+func LoadMovingAIScenarios(r io.Reader) ([]*MovingAIScenario, error) {
+	scenarios := make([]*MovingAIScenario, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || fields[0] == "version" {
+			continue
+		}
+
+		if len(fields) != 9 {
+			return nil, fmt.Errorf("map loader: bad scenario line %q", scanner.Text())
+		}
+
+		values := make([]int, 0, 7)
+		for _, field := range append([]string{fields[0]}, fields[2:8]...) {
+			value, err := strconv.Atoi(field)
+			if err != nil {
+				return nil, fmt.Errorf("map loader: bad scenario line %q", scanner.Text())
+			}
+
+			values = append(values, value)
+		}
+
+		optimalLength, err := strconv.ParseFloat(fields[8], 64)
+		if err != nil {
+			return nil, fmt.Errorf("map loader: bad scenario line %q", scanner.Text())
+		}
+
+		scenarios = append(scenarios, &MovingAIScenario{
+			Bucket:        values[0],
+			MapName:       fields[1],
+			Cols:          values[1],
+			Rows:          values[2],
+			StartGrid:     Grid{Col: values[3], Row: values[4]},
+			DstGrid:       Grid{Col: values[5], Row: values[6]},
+			OptimalLength: optimalLength,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return scenarios, nil
+}
+
+//========================
+//        Png Map
+//========================
+// a pixel for a grid, the dark pixel (gray < 128) or the transparent pixel can't be crossed,
+// the others can be crossed with g value 1
+func LoadPngMap(r io.Reader) (*GridMap, error) {
+	img, err := png.Decode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGridMapFromImage(img), nil
+}
+
+func NewGridMapFromImage(img image.Image) *GridMap {
+	bounds := img.Bounds()
+	m := NewGridMap(uint32(bounds.Dx()), uint32(bounds.Dy()), 1)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			pixel := img.At(x, y)
+			_, _, _, alpha := pixel.RGBA()
+			gray := color.GrayModel.Convert(pixel).(color.Gray)
+			if alpha < 0x8000 || gray.Y < 128 {
+				m.SetCanCross(x-bounds.Min.X, y-bounds.Min.Y, false)
+			}
+		}
+	}
+
+	return m
+}
+
+func newGridMapFromLines(lines []string, rule func(c byte) (uint32, bool)) (*GridMap, error) {
+	if len(lines) == 0 {
+		return nil, ErrMapEmpty
+	}
+
+	cols := 0
+	for _, line := range lines {
+		cols = maxInt(cols, len(line))
+	}
+
+	if cols == 0 {
+		return nil, ErrMapEmpty
+	}
+
+	m := NewGridMap(uint32(cols), uint32(len(lines)), 1)
+	for row, line := range lines {
+		for col := 0; col < cols; col++ {
+			if col >= len(line) {
+				m.SetCanCross(col, row, false)
+				continue
+			}
+
+			gValue, ok := rule(line[col])
+			m.SetCanCross(col, row, ok)
+			if ok {
+				m.SetGValue(col, row, gValue)
+			}
+		}
+	}
+
+	return m, nil
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestLoadAsciiMap(t *testing.T) {
+	m, err := LoadAsciiMap(strings.NewReader("..#\r\n.5\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cols, rows := m.GetColRow()
+	if cols != 3 || rows != 2 {
+		t.Fatalf("got size %dx%d, want 3x2", cols, rows)
+	}
+
+	// the short line is padded with the walls
+	if m.CanCross(2, 0) || m.CanCross(2, 1) || !m.CanCross(1, 1) || m.GetGValue(1, 1) != 5 {
+		t.Error("the grids differ")
+	}
+
+	if _, err := LoadAsciiMap(strings.NewReader("\n\n")); err != ErrMapEmpty {
+		t.Errorf("got error %v, want %v", err, ErrMapEmpty)
+	}
+}
+
+func TestLoadMovingAIMap(t *testing.T) {
+	m, err := LoadMovingAIMap(strings.NewReader("type octile\nheight 2\nwidth 3\nmap\n.@G\nTS.\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cols, rows := m.GetColRow()
+	if cols != 3 || rows != 2 || m.CanCross(1, 0) || !m.CanCross(2, 0) || m.CanCross(0, 1) || !m.CanCross(1, 1) {
+		t.Error("the grids differ")
+	}
+
+	cases := []struct {
+		name string
+		data string
+	}{
+		{"no width", "type octile\nheight 1\nmap\n...\n"},
+		{"bad height", "type octile\nheight x\nwidth 3\nmap\n...\n"},
+		{"long line", "type octile\nheight 1\nwidth 3\nmap\n.......\n"},
+		{"short line", "type octile\nheight 2\nwidth 3\nmap\n...\n..\n"},
+	}
+
+	for _, c := range cases {
+		if _, err := LoadMovingAIMap(strings.NewReader(c.data)); err != ErrMapBadHeader {
+			t.Errorf("%s: got error %v, want %v", c.name, err, ErrMapBadHeader)
+		}
+	}
+
+	// the huge height is not allocated
+	if _, err := LoadMovingAIMap(strings.NewReader("type octile\nheight 4000000000\nwidth 3\nmap\n...\n")); err == nil {
+		t.Error("the missing rows are not reported")
+	}
+}
+
+func TestLoadMovingAIScenarios(t *testing.T) {
+	scenarios, err := LoadMovingAIScenarios(strings.NewReader("version 1\n3\tm.map\t8\t6\t1\t2\t5\t4\t4.82842712\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(scenarios) != 1 {
+		t.Fatalf("got %d scenarios, want 1", len(scenarios))
+	}
+
+	s := scenarios[0]
+	if s.Bucket != 3 || s.MapName != "m.map" || s.Cols != 8 || s.Rows != 6 || s.StartGrid != (Grid{Col: 1, Row: 2}) ||
+		s.DstGrid != (Grid{Col: 5, Row: 4}) || s.OptimalLength != 4.82842712 {
+		t.Errorf("got scenario %+v", s)
+	}
+
+	if _, err := LoadMovingAIScenarios(strings.NewReader("3 m.map 8 6 1 2 5 4\n")); err == nil {
+		t.Error("the short line is not reported")
+	}
+}
+
+func TestLoadPngMap(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 3; x++ {
+			img.Set(x, y, color.White)
+		}
+	}
+
+	img.Set(1, 0, color.Black)
+	img.Set(2, 1, color.Transparent)
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := LoadPngMap(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cols, rows := m.GetColRow()
+	if cols != 3 || rows != 2 || m.CanCross(1, 0) || m.CanCross(2, 1) || !m.CanCross(0, 0) || m.GetGValue(0, 0) != 1 {
+		t.Error("the grids differ")
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "time"
+
+//========================
+//     SearchObserver
+//========================
+// called by the finder during a search, keep the callbacks cheap since they
+// are called for every node
+type SearchObserver interface {
+	// a node is added to open list
+	OnNodeOpened(node PathNode)
+	// a node is popped from open list and unfolded
+	OnNodeExpanded(node PathNode)
+	// a lower g value of an existing node is found, the node has the new g value and parent
+	OnNodeImproved(node PathNode, oldGValue uint32)
+	// a jump point is found by a scan from the parent
+	OnJumpPointFound(parent PathNode, col int, row int, gValue uint32)
+	OnSearchFinished(stats *SearchStats)
+}
+
+// the empty callbacks, embed it to implement only the needed ones
+type BaseSearchObserver struct {
+}
+
+func (o *BaseSearchObserver) OnNodeOpened(node PathNode) {
+}
+
+func (o *BaseSearchObserver) OnNodeExpanded(node PathNode) {
+}
+
+func (o *BaseSearchObserver) OnNodeImproved(node PathNode, oldGValue uint32) {
+}
+
+func (o *BaseSearchObserver) OnJumpPointFound(parent PathNode, col int, row int, gValue uint32) {
+}
+
+func (o *BaseSearchObserver) OnSearchFinished(stats *SearchStats) {
+}
+
+//========================
+//      SearchStats
+//========================
+type SearchStats struct {
+	// the nodes popped from open list
+	ExpandedCount int
+	// the new nodes added to open list
+	GeneratedCount int
+	// the existing nodes got a lower g value
+	ImprovedCount int
+	// the max size of open list
+	PeakOpenCount int
+	// the grids scanned or jumped over by the jump point search
+	JumpScanLength int
+	// the count of the nodes and the g value of the path
+	PathLength int
+	PathGValue uint32
+	Found      bool
+	Elapsed    time.Duration
+}
+
+func (s *SearchStats) reset() {
+	*s = SearchStats{}
+}
+
+// report each stat as a metric, the name is in snake case
+func (s *SearchStats) Export(report func(name string, value float64)) {
+	report("expanded_nodes", float64(s.ExpandedCount))
+	report("generated_nodes", float64(s.GeneratedCount))
+	report("improved_nodes", float64(s.ImprovedCount))
+	report("peak_open_nodes", float64(s.PeakOpenCount))
+	report("jump_scan_length", float64(s.JumpScanLength))
+	report("path_length", float64(s.PathLength))
+	report("path_g_value", float64(s.PathGValue))
+	report("found", boolToFloat(s.Found))
+	report("elapsed_seconds", s.Elapsed.Seconds())
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
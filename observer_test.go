@@ -0,0 +1,48 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+type improvedChecker struct {
+	BaseSearchObserver
+	t     *testing.T
+	m     NavigationMap
+	count int
+}
+
+func (c *improvedChecker) OnNodeImproved(node PathNode, oldGValue uint32) {
+	c.count++
+	gValue := node.GetMinGValue()
+	if gValue >= oldGValue {
+		c.t.Errorf("improved g value %d is not lower than the old one %d", gValue, oldGValue)
+	}
+
+	parent := node.GetParent()
+	if parent == nil {
+		c.t.Fatalf("improved node has no parent")
+	}
+
+	grid := node.GetGrid()
+	parentGrid := parent.GetGrid()
+	addGValue, _ := getEdgeGValue(c.m, parentGrid.Col, parentGrid.Row, grid.Col, grid.Row)
+	if parent.GetMinGValue()+addGValue != gValue {
+		c.t.Errorf("improved g value %d doesn't come from the new parent %d + %d", gValue, parent.GetMinGValue(), addGValue)
+	}
+}
+
+func TestObserverImprovedNode(t *testing.T) {
+	m := newRandomTestMap(1, 32, 32, 0.2, 9)
+	checker := &improvedChecker{t: t, m: m}
+	finder := NewAStar()
+	finder.SetObserver(checker)
+	for i := 0; i < 32; i++ {
+		finder.FindPath(m, NewGrid(i, 0), NewGrid(31-i, 31))
+	}
+
+	if checker.count == 0 {
+		t.Errorf("no node is improved")
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"container/list"
+	"math"
+)
+
+//========================
+//      VersionedMap
+//========================
+// optional for a navigation map, the version changes after any grid changed
+type VersionedMap interface {
+	NavigationMap
+	GetVersion() uint64
+}
+
+func getMapVersion(m NavigationMap) (uint64, bool) {
+	versionedMap, ok := m.(VersionedMap)
+	if !ok {
+		return 0, false
+	}
+
+	return versionedMap.GetVersion(), true
+}
+
+// the finder tells which grids it searched
+type searchedNodesFinder interface {
+	GetSearchedNodes() []PathNode
+}
+
+// the finder tells the bounds of the grids it scanned without the nodes
+type scannedBoundsFinder interface {
+	GetScannedBounds() (left int, top int, right int, bottom int, ok bool)
+}
+
+//========================
+//       PathCache
+//========================
+// the maps are compared by identity, so the map should be a pointer
+type pathCacheKey struct {
+	m         NavigationMap
+	version   uint64
+	startGrid Grid
+	dstGrid   Grid
+	profile   *MovementProfile
+}
+
+type pathCacheEntry struct {
+	key      pathCacheKey
+	fullPath []PathNode
+	bSucc    bool
+	// the bounds of the path and the searched grids
+	left   int
+	top    int
+	right  int
+	bottom int
+}
+
+// keep the recent paths of a finder, the paths are shared by the callers and should not be modified
+//
+// the entries are kept by the map and its version, the entries of the old versions are not found any more.
+// an entry is dropped when a dirty rect touched the path or the searched grids,
+// for the jump point finders the searched grids include the grids scanned by the jumps
+type PathCache struct {
+	finder        PathFinder
+	capacity      int
+	entries       map[pathCacheKey]*list.Element
+	lru           *list.List
+	bCheckVersion bool
+	hitCount      uint64
+	missCount     uint64
+}
+
+func NewPathCache(finder PathFinder, capacity int) *PathCache {
+	if capacity <= 0 {
+		capacity = 1
+	}
+
+	return &PathCache{
+		finder:        finder,
+		capacity:      capacity,
+		entries:       make(map[pathCacheKey]*list.Element),
+		lru:           list.New(),
+		bCheckVersion: true,
+	}
+}
+
+func (c *PathCache) GetFinder() PathFinder {
+	return c.finder
+}
+
+// find the entries of the current map version only, turn it off if the changes are reported by MarkDirty
+func (c *PathCache) SetVersionCheck(bCheckVersion bool) {
+	c.bCheckVersion = bCheckVersion
+}
+
+func (c *PathCache) GetCount() int {
+	return c.lru.Len()
+}
+
+func (c *PathCache) GetHitCount() uint64 {
+	return c.hitCount
+}
+
+func (c *PathCache) GetMissCount() uint64 {
+	return c.missCount
+}
+
+func (c *PathCache) Reset() {
+	c.finder.Reset()
+}
+
+// drop all the entries
+func (c *PathCache) Clear() {
+	c.entries = make(map[pathCacheKey]*list.Element)
+	c.lru.Init()
+}
+
+func (c *PathCache) FindPath(m NavigationMap, startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	return c.findPath(m, m, nil, startGrid, dstGrid)
+}
+
+func (c *PathCache) FindPathWithProfile(m TerrainMap, profile *MovementProfile, startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	return c.findPath(m, NewProfileMap(m, profile), profile, startGrid, dstGrid)
+}
+
+func (c *PathCache) GridChanged(col int, row int) {
+	c.MarkDirty(col, row, col, row)
+}
+
+// drop the entries touched by the changed grids in the rect, the entries of all the maps are checked
+func (c *PathCache) MarkDirty(left int, top int, right int, bottom int) {
+	for e := c.lru.Front(); e != nil; {
+		next := e.Next()
+		entry := e.Value.(*pathCacheEntry)
+		if entry.left <= right && left <= entry.right && entry.top <= bottom && top <= entry.bottom {
+			c.remove(e)
+		}
+
+		e = next
+	}
+}
+
+// versionMap is the map has the version, findMap is the map to find path on
+func (c *PathCache) findPath(versionMap NavigationMap, findMap NavigationMap, profile *MovementProfile, startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	key := pathCacheKey{
+		m:         versionMap,
+		startGrid: *startGrid,
+		dstGrid:   *dstGrid,
+		profile:   profile,
+	}
+
+	if c.bCheckVersion {
+		key.version, _ = getMapVersion(versionMap)
+	}
+
+	e, ok := c.entries[key]
+	if ok {
+		entry := e.Value.(*pathCacheEntry)
+		c.hitCount++
+		c.lru.MoveToFront(e)
+		return entry.fullPath, entry.bSucc
+	}
+
+	c.missCount++
+	fullPath, bSucc := c.finder.FindPath(findMap, startGrid, dstGrid)
+	entry := &pathCacheEntry{
+		key:      key,
+		fullPath: fullPath,
+		bSucc:    bSucc,
+	}
+
+	// the nodes of the finder are reused by the next search
+	c.setBounds(entry, startGrid, dstGrid)
+	entry.fullPath = ClonePath(fullPath)
+	c.add(entry)
+	return entry.fullPath, bSucc
+}
+
+// the bounds grow one grid, so a changed neighbour of the searched grids touches the entry
+func (c *PathCache) setBounds(entry *pathCacheEntry, startGrid *Grid, dstGrid *Grid) {
+	finder, ok := c.finder.(searchedNodesFinder)
+	if !ok {
+		// don't know where the finder searched
+		entry.left = math.MinInt32
+		entry.top = math.MinInt32
+		entry.right = math.MaxInt32
+		entry.bottom = math.MaxInt32
+		return
+	}
+
+	entry.left = minInt(startGrid.Col, dstGrid.Col)
+	entry.top = minInt(startGrid.Row, dstGrid.Row)
+	entry.right = maxInt(startGrid.Col, dstGrid.Col)
+	entry.bottom = maxInt(startGrid.Row, dstGrid.Row)
+	for _, nodes := range [][]PathNode{entry.fullPath, finder.GetSearchedNodes()} {
+		for _, node := range nodes {
+			grid := node.GetGrid()
+			entry.left = minInt(entry.left, grid.Col)
+			entry.top = minInt(entry.top, grid.Row)
+			entry.right = maxInt(entry.right, grid.Col)
+			entry.bottom = maxInt(entry.bottom, grid.Row)
+		}
+	}
+
+	boundsFinder, ok := c.finder.(scannedBoundsFinder)
+	if ok {
+		left, top, right, bottom, bScanned := boundsFinder.GetScannedBounds()
+		if bScanned {
+			entry.left = minInt(entry.left, left)
+			entry.top = minInt(entry.top, top)
+			entry.right = maxInt(entry.right, right)
+			entry.bottom = maxInt(entry.bottom, bottom)
+		}
+	}
+
+	entry.left--
+	entry.top--
+	entry.right++
+	entry.bottom++
+}
+
+func (c *PathCache) add(entry *pathCacheEntry) {
+	for c.lru.Len() >= c.capacity {
+		c.remove(c.lru.Back())
+	}
+
+	c.entries[entry.key] = c.lru.PushFront(entry)
+}
+
+func (c *PathCache) remove(e *list.Element) {
+	entry := e.Value.(*pathCacheEntry)
+	delete(c.entries, entry.key)
+	c.lru.Remove(e)
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+func TestPathCacheGridChanged(t *testing.T) {
+	newFinders := map[string]func(m NavigationMap) PathFinder{
+		"astar":   func(m NavigationMap) PathFinder { return NewAStar() },
+		"jps":     func(m NavigationMap) PathFinder { return NewJps(0, false) },
+		"jps8":    func(m NavigationMap) PathFinder { return NewJps(0, true) },
+		"jpsplus": func(m NavigationMap) PathFinder { return NewJpsPlus(NewJpsPlusTable(m, false)) },
+	}
+
+	for name, newFinder := range newFinders {
+		// the wall has a gap at the right end only
+		m := NewGridMap(30, 3, 1)
+		for col := 0; col < 29; col++ {
+			m.SetCanCross(col, 1, false)
+		}
+
+		cache := NewPathCache(newFinder(m), 8)
+		cache.SetVersionCheck(false)
+		startGrid := NewGrid(10, 2)
+		dstGrid := NewGrid(10, 0)
+		if _, ok := cache.FindPath(m, startGrid, dstGrid); !ok {
+			t.Fatalf("%s: no path", name)
+		}
+
+		// open a gap far from the jump points
+		m.SetCanCross(1, 1, true)
+		cache.GridChanged(1, 1)
+		if cache.GetCount() != 0 {
+			t.Errorf("%s: the path is still cached", name)
+		}
+
+		// the table of jps plus is built for the old map
+		if name == "jpsplus" {
+			continue
+		}
+
+		got, ok := getPathGValue(t, cache, m, startGrid, dstGrid)
+		want, _ := getPathGValue(t, newFinder(m), m, startGrid, dstGrid)
+		if !ok || got != want {
+			t.Errorf("%s: got g value %d (%v) from the cache, want %d", name, got, ok, want)
+		}
+	}
+}
+
+func TestPathCacheMaps(t *testing.T) {
+	open := newTestMap(t, "...", "...", "...")
+	walled := newTestMap(t, "...", "###", "...")
+	for _, bCheckVersion := range []bool{false, true} {
+		cache := NewPathCache(NewAStar(), 8)
+		cache.SetVersionCheck(bCheckVersion)
+		if _, ok := cache.FindPath(open, NewGrid(0, 0), NewGrid(0, 2)); !ok {
+			t.Fatal("no path on the open map")
+		}
+
+		if _, ok := cache.FindPath(walled, NewGrid(0, 0), NewGrid(0, 2)); ok {
+			t.Errorf("version check %v: the path of the open map is found on the walled map", bCheckVersion)
+		}
+	}
+
+	// the version is a part of the key
+	cache := NewPathCache(NewAStar(), 8)
+	cache.FindPath(open, NewGrid(0, 0), NewGrid(0, 2))
+	open.SetCanCross(1, 1, false)
+	if _, ok := cache.FindPath(open, NewGrid(0, 0), NewGrid(0, 2)); !ok || cache.GetHitCount() != 0 {
+		t.Errorf("got %d hits, want 0", cache.GetHitCount())
+	}
+}
+
+// the jumps of jps plus stay in the walled room
+func TestPathCacheJpsPlusBounds(t *testing.T) {
+	m := NewGridMap(30, 30, 1)
+	for i := 0; i <= 10; i++ {
+		m.SetCanCross(10, i, false)
+		m.SetCanCross(i, 10, false)
+	}
+
+	for _, bCanObliqueMove := range []bool{false, true} {
+		cache := NewPathCache(NewJpsPlus(NewJpsPlusTable(m, bCanObliqueMove)), 8)
+		cache.SetVersionCheck(false)
+		if _, ok := cache.FindPath(m, NewGrid(1, 1), NewGrid(5, 5)); !ok {
+			t.Fatal("no path")
+		}
+
+		cache.MarkDirty(20, 20, 25, 25)
+		if cache.GetCount() != 1 {
+			t.Errorf("oblique %v: the path is dropped by the change out of the room", bCanObliqueMove)
+		}
+
+		cache.MarkDirty(10, 3, 10, 3)
+		if cache.GetCount() != 0 {
+			t.Errorf("oblique %v: the path is kept after the wall changed", bCanObliqueMove)
+		}
+	}
+}
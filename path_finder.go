@@ -4,7 +4,11 @@
 
 package nav
 
-import "math"
+import (
+	"errors"
+	"math"
+	"time"
+)
 
 //========================
 //      NavigationMap
@@ -16,6 +20,53 @@ type NavigationMap interface {
 	GetMinGValue() uint32
 }
 
+//========================
+//      UnboundedMap
+//========================
+// optional for a navigation map, the map has no global bounds and GetColRow
+// is meaningless, CanCross returns false for the grids can't be reached
+type UnboundedMap interface {
+	NavigationMap
+	IsUnbounded() bool
+}
+
+const (
+	// the default max expanded count of a search on an unbounded map, so the
+	// search for an unreachable dest grid stops
+	UnboundedMaxExpandedCount = 100000
+	// the default max grids of a jump point scan on an unbounded map
+	UnboundedMaxOrthogonalDeep = 64
+)
+
+func isUnbounded(m NavigationMap) bool {
+	unboundedMap, ok := m.(UnboundedMap)
+	if !ok {
+		return false
+	}
+
+	return unboundedMap.IsUnbounded()
+}
+
+// the data built for each grid of the map, such as ClearanceMap, needs the bounds
+var ErrUnboundedMap = errors.New("nav: the map is unbounded")
+
+// panic if the map is unbounded, for the builders of the data of each grid
+func checkBoundedMap(m NavigationMap) {
+	if isUnbounded(m) {
+		panic(ErrUnboundedMap)
+	}
+}
+
+// the grid is in the bounds of the map, always true for an unbounded map
+func isInMapBounds(m NavigationMap, col int, row int) bool {
+	if isUnbounded(m) {
+		return true
+	}
+
+	maxCol, maxRow := m.GetColRow()
+	return col >= 0 && row >= 0 && col < int(maxCol) && row < int(maxRow)
+}
+
 //========================
 //      PathNode
 //========================
@@ -32,28 +83,64 @@ type PathNode interface {
 	// UpdateChildrenGValue(m NavigationMap)
 }
 
+// the optional methods of a node, the nodes embed BasePathNode have them
+type linkNode interface {
+	SetLink(link *Link)
+	GetLink() *Link
+}
+
+type resettableNode interface {
+	ResetMinGValue(minGValue uint32)
+}
+
+func setNodeLink(node PathNode, link *Link) {
+	n, ok := node.(linkNode)
+	if ok {
+		n.SetLink(link)
+	}
+}
+
+// the link from the parent to the node, nil if it is a normal move
+func GetNodeLink(node PathNode) *Link {
+	n, ok := node.(linkNode)
+	if !ok {
+		return nil
+	}
+
+	return n.GetLink()
+}
+
 type BasePathNode struct {
 	parent    PathNode
 	vecParent *Vector
 	children  []PathNode
 	minGValue uint32
 	grid      *Grid
+	link      *Link
 }
 
 func NewBasePathNode(parent PathNode, vecParent *Vector, minGValue uint32, col int, row int) *BasePathNode {
 	n := &BasePathNode{
-		parent:    parent,
-		vecParent: vecParent,
-		children:  make([]PathNode, 0),
-		minGValue: minGValue,
-		grid:      NewGrid(col, row),
+		children: make([]PathNode, 0),
+		grid:     NewGrid(col, row),
 	}
 
+	n.Init(parent, vecParent, minGValue, col, row)
+	return n
+}
+
+// init the node as a new one, so it can be reused by another search
+func (n *BasePathNode) Init(parent PathNode, vecParent *Vector, minGValue uint32, col int, row int) {
+	n.parent = parent
+	n.vecParent = vecParent
+	n.children = n.children[:0]
+	n.minGValue = minGValue
+	n.grid.Update(col, row)
+	n.link = nil
+
 	if n.parent != nil {
 		n.parent.AddChild(n)
 	}
-
-	return n
 }
 
 func (n *BasePathNode) SetParent(parent PathNode) {
@@ -71,6 +158,10 @@ func (n *BasePathNode) UpdateParent(parent PathNode, vecParent *Vector) {
 
 	n.parent = parent
 	n.vecParent = vecParent
+
+	if n.parent != nil {
+		n.parent.AddChild(n)
+	}
 }
 
 // func (n *BasePathNode) SetParentVector(vec *Vector) {
@@ -89,6 +180,15 @@ func (n *BasePathNode) AddChild(node PathNode) {
 	n.children = append(n.children, node)
 }
 
+func (n *BasePathNode) GetChildren() []PathNode {
+	return n.children
+}
+
+// the children are added as the base nodes, compare the nodes by them
+func (n *BasePathNode) getBaseNode() *BasePathNode {
+	return n
+}
+
 func (n *BasePathNode) RemoveChild(node PathNode) {
 	if node == nil {
 		return
@@ -108,6 +208,11 @@ func (n *BasePathNode) SetMinGValue(minGValue uint32, m NavigationMap) {
 	n.UpdateChildrenGValue(m, gValueChange)
 }
 
+// only update the g value of this node, children will be updated when it unfold again
+func (n *BasePathNode) ResetMinGValue(minGValue uint32) {
+	n.minGValue = minGValue
+}
+
 func (n *BasePathNode) GetMinGValue() uint32 {
 	return n.minGValue
 }
@@ -116,6 +221,15 @@ func (n *BasePathNode) GetGrid() *Grid {
 	return n.grid
 }
 
+// the link from parent to this node, nil if it is a normal move
+func (n *BasePathNode) SetLink(link *Link) {
+	n.link = link
+}
+
+func (n *BasePathNode) GetLink() *Link {
+	return n.link
+}
+
 func (n *BasePathNode) UpdateChildrenGValue(m NavigationMap, gValueChange int) {
 	for _, child := range n.children {
 		oldMinGValue := child.GetMinGValue()
@@ -128,6 +242,117 @@ func (n *BasePathNode) UpdateChildrenGValue(m NavigationMap, gValueChange int) {
 	}
 }
 
+//========================
+//      UpdatePolicy
+//========================
+// how to handle a node in close list when a lower g value is found
+type UpdatePolicy int
+
+const (
+	// propagate the g value change through the children tree, and unfold the closed
+	// nodes of the tree again for their other neighbours
+	UpdatePolicyPropagate UpdatePolicy = iota
+	// move the node back to open list and unfold it again
+	UpdatePolicyReopen
+	// keep the node unchanged, only safe when the heuristic is consistent
+	UpdatePolicyIgnore
+)
+
+//========================
+//      Heuristic
+//========================
+type HeuristicFunc func(grid *Grid, dstGrid *Grid, baseGValue uint32) uint32
+
+// for 4 directions move, or oblique move costs two grids
+func ManhattanHeuristic(grid *Grid, dstGrid *Grid, baseGValue uint32) uint32 {
+	xAbs := math.Abs(float64(dstGrid.Col-grid.Col) * float64(baseGValue))
+	yAbs := math.Abs(float64(dstGrid.Row-grid.Row) * float64(baseGValue))
+	return uint32(xAbs + yAbs)
+}
+
+// for oblique move costs the same as orthogonal move
+func ChebyshevHeuristic(grid *Grid, dstGrid *Grid, baseGValue uint32) uint32 {
+	xAbs := math.Abs(float64(dstGrid.Col-grid.Col) * float64(baseGValue))
+	yAbs := math.Abs(float64(dstGrid.Row-grid.Row) * float64(baseGValue))
+	return uint32(math.Max(xAbs, yAbs))
+}
+
+// no heuristic, AStar pops the grids in the order of g value and stops when the dest
+// grid is popped, the same as dijkstra
+func ZeroHeuristic(grid *Grid, dstGrid *Grid, baseGValue uint32) uint32 {
+	return 0
+}
+
+//========================
+//      NeighbourMap
+//========================
+// optional for a navigation map, replace the 4 orthogonal neighbours of AStar
+type NeighbourMap interface {
+	NavigationMap
+	GetNeighbours(col int, row int) []*Grid
+}
+
+// the neighbours decided by the map, or the 4 orthogonal neighbours
+func getNeighbours(m NavigationMap, col int, row int) []*Grid {
+	neighbourMap, ok := m.(NeighbourMap)
+	if ok {
+		return neighbourMap.GetNeighbours(col, row)
+	}
+
+	return []*Grid{NewGrid(col-1, row), NewGrid(col+1, row), NewGrid(col, row-1), NewGrid(col, row+1)}
+}
+
+//========================
+//      HeuristicMap
+//========================
+// optional for a navigation map, adjust the heuristic of the finder to keep it admissible
+type HeuristicMap interface {
+	NavigationMap
+	GetHValue(grid *Grid, dstGrid *Grid, baseGValue uint32, heuristic HeuristicFunc) uint32
+}
+
+func getHValue(m NavigationMap, grid *Grid, dstGrid *Grid, baseGValue uint32, heuristic HeuristicFunc) uint32 {
+	heuristicMap, ok := m.(HeuristicMap)
+	if ok {
+		return heuristicMap.GetHValue(grid, dstGrid, baseGValue, heuristic)
+	}
+
+	return heuristic(grid, dstGrid, baseGValue)
+}
+
+//========================
+//      mapForwarder
+//========================
+// embedded by the views of a navigation map to forward the optional interfaces of the
+// inner map, the view keeps the defaults if the inner map doesn't implement them
+type mapForwarder struct {
+	inner NavigationMap
+}
+
+func (f mapForwarder) GetEdgeGValue(fromCol int, fromRow int, toCol int, toRow int) (uint32, bool) {
+	return getEdgeGValue(f.inner, fromCol, fromRow, toCol, toRow)
+}
+
+func (f mapForwarder) HasEdgeCost(col int, row int) bool {
+	return hasEdgeCost(f.inner, col, row)
+}
+
+func (f mapForwarder) GetLinks(col int, row int) []*Link {
+	return getLinks(f.inner, col, row)
+}
+
+func (f mapForwarder) GetNeighbours(col int, row int) []*Grid {
+	return getNeighbours(f.inner, col, row)
+}
+
+func (f mapForwarder) GetHValue(grid *Grid, dstGrid *Grid, baseGValue uint32, heuristic HeuristicFunc) uint32 {
+	return getHValue(f.inner, grid, dstGrid, baseGValue, heuristic)
+}
+
+func (f mapForwarder) IsUnbounded() bool {
+	return isUnbounded(f.inner)
+}
+
 //========================
 //      PathFinder
 //========================
@@ -142,32 +367,228 @@ type PathFinderImpl interface {
 	// GetFullPath() ([]*Grid, bool)
 }
 
+// a node need to clear its unfold state before reopen
+type reopenableNode interface {
+	ResetUnfold()
+}
+
+type parentNode interface {
+	GetChildren() []PathNode
+	getBaseNode() *BasePathNode
+}
+
+// copy the path, the copy doesn't share any node with the finder
+func ClonePath(fullPath []PathNode) []PathNode {
+	if fullPath == nil {
+		return nil
+	}
+
+	clonePath := make([]PathNode, 0, len(fullPath))
+	var parent PathNode
+	for _, node := range fullPath {
+		grid := node.GetGrid()
+		clone := NewBasePathNode(parent, node.GetParentVector(), node.GetMinGValue(), grid.Col, grid.Row)
+		clone.SetLink(GetNodeLink(node))
+		clonePath = append(clonePath, clone)
+		parent = clone
+	}
+
+	return clonePath
+}
+
 //========================
 //     BasePathFinder
 //========================
+type openEntry struct {
+	node   PathNode
+	fValue uint32
+	seq    uint64
+}
+
+// the lists, the indexes and the nodes are kept between the searches, so the
+// repeated searches allocate little. FindPath returns a copy of the path, unless
+// SetPathReuse is on
 type BasePathFinder struct {
-	openList  []PathNode
-	closeList []PathNode
-	lastNode  PathNode
-	impl      PathFinderImpl
+	openList       []openEntry
+	openIndex      map[Grid]PathNode
+	closeIndex     map[Grid]PathNode
+	lastNode       PathNode
+	impl           PathFinderImpl
+	updatePolicy   UpdatePolicy
+	heuristic      HeuristicFunc
+	componentIndex *ComponentIndex
+	clearanceMap   *ClearanceMap
+	agentSize      uint32
+	maxExpanded    int
+	bPathReuse     bool
+	nodePool       []PathNode
+	nodeCount      int
+	fullPath       []PathNode
+	seq            uint64
+	bOpenDirty     bool
+	searchMap      NavigationMap
+	dstGrid        Grid
+	baseGValue     uint32
+	observer       SearchObserver
+	stats          SearchStats
+	// the bounds of the grids scanned between the nodes, such as the jumps of jps
+	scanLeft   int
+	scanTop    int
+	scanRight  int
+	scanBottom int
+	bScanned   bool
 }
 
 func NewBasePathFinder(impl PathFinderImpl) *BasePathFinder {
 	return &BasePathFinder{
-		openList:  make([]PathNode, 0),
-		closeList: make([]PathNode, 0),
-		lastNode:  nil,
-		impl:      impl,
+		openList:     make([]openEntry, 0),
+		openIndex:    make(map[Grid]PathNode),
+		closeIndex:   make(map[Grid]PathNode),
+		lastNode:     nil,
+		impl:         impl,
+		updatePolicy: UpdatePolicyPropagate,
+		heuristic:    ManhattanHeuristic,
+		nodePool:     make([]PathNode, 0),
+		fullPath:     make([]PathNode, 0),
 	}
 }
 
+func (f *BasePathFinder) SetUpdatePolicy(policy UpdatePolicy) {
+	f.updatePolicy = policy
+}
+
+func (f *BasePathFinder) GetUpdatePolicy() UpdatePolicy {
+	return f.updatePolicy
+}
+
+func (f *BasePathFinder) SetHeuristic(heuristic HeuristicFunc) {
+	f.heuristic = heuristic
+}
+
+func (f *BasePathFinder) GetHeuristic() HeuristicFunc {
+	return f.heuristic
+}
+
+// the search fails after expanding the count of nodes, 0 means no limit, but a search
+// on an unbounded map is limited by UnboundedMaxExpandedCount
+func (f *BasePathFinder) SetMaxExpandedCount(count int) {
+	f.maxExpanded = count
+}
+
+func (f *BasePathFinder) GetMaxExpandedCount() int {
+	return f.maxExpanded
+}
+
+// return the nodes of the finder as the path without copying, they are changed by the
+// next search, so the path should be used or copied by ClonePath before it
+func (f *BasePathFinder) SetPathReuse(bPathReuse bool) {
+	f.bPathReuse = bPathReuse
+}
+
+func (f *BasePathFinder) IsPathReuse() bool {
+	return f.bPathReuse
+}
+
+// observe the searches, nil to remove
+func (f *BasePathFinder) SetObserver(observer SearchObserver) {
+	f.observer = observer
+}
+
+// the stats of the last search
+func (f *BasePathFinder) GetStats() *SearchStats {
+	return &f.stats
+}
+
+// reject the unreachable queries before searching
+func (f *BasePathFinder) SetComponentIndex(componentIndex *ComponentIndex) {
+	f.componentIndex = componentIndex
+}
+
+// only find the path the agent with the size can go through,
+// the grids of the path are the left top corner of the agent
+func (f *BasePathFinder) SetAgentSize(clearanceMap *ClearanceMap, agentSize uint32) {
+	f.clearanceMap = clearanceMap
+	f.agentSize = agentSize
+}
+
+func (f *BasePathFinder) GetAgentSize() uint32 {
+	if f.clearanceMap == nil || f.agentSize == 0 {
+		return 1
+	}
+
+	return f.agentSize
+}
+
+// clear the state of the last search, FindPath calls it before searching
 func (f *BasePathFinder) Reset() {
-	f.openList = make([]PathNode, 0)
-	f.closeList = make([]PathNode, 0)
+	f.openList = f.openList[:0]
+	for grid := range f.openIndex {
+		delete(f.openIndex, grid)
+	}
+
+	for grid := range f.closeIndex {
+		delete(f.closeIndex, grid)
+	}
+
 	f.lastNode = nil
+	f.nodeCount = 0
+	f.seq = 0
+	f.bOpenDirty = false
+	f.searchMap = nil
+	f.bScanned = false
+	f.stats.reset()
+}
+
+// a node created by the former searches, the impl should init it before use
+func (f *BasePathFinder) GetPooledNode() (PathNode, bool) {
+	if f.nodeCount >= len(f.nodePool) {
+		return nil, false
+	}
+
+	node := f.nodePool[f.nodeCount]
+	f.nodeCount++
+	return node, true
+}
+
+// keep a new node, so the next searches can reuse it
+func (f *BasePathFinder) AddNodeToPool(node PathNode) {
+	f.nodePool = append(f.nodePool, node)
+	f.nodeCount++
 }
 
 func (f *BasePathFinder) FindPath(m NavigationMap, startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	f.Reset()
+	startTime := time.Now()
+	fullPath, bSucc := f.search(m, startGrid, dstGrid)
+
+	f.stats.Elapsed = time.Since(startTime)
+	f.stats.Found = bSucc
+	if bSucc {
+		f.stats.PathLength = len(fullPath)
+		f.stats.PathGValue = fullPath[len(fullPath)-1].GetMinGValue()
+	}
+
+	if f.observer != nil {
+		f.observer.OnSearchFinished(&f.stats)
+	}
+
+	if !f.bPathReuse {
+		return ClonePath(fullPath), bSucc
+	}
+
+	return fullPath, bSucc
+}
+
+func (f *BasePathFinder) search(m NavigationMap, startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	// big agent
+	if f.GetAgentSize() > 1 {
+		m = NewAgentSizeMap(m, f.clearanceMap, f.agentSize)
+	}
+
+	f.searchMap = m
+	f.dstGrid = *dstGrid
+	f.baseGValue = m.GetMinGValue()
+
 	// pre check
 	fullPath, bSucc, bFinish := f.preCheck(m, startGrid, dstGrid)
 	if bFinish {
@@ -178,6 +599,11 @@ func (f *BasePathFinder) FindPath(m NavigationMap, startGrid *Grid, dstGrid *Gri
 	firstNode := f.impl.CreateFirstNode(startGrid.Col, startGrid.Row)
 	f.AddNodeToOpenList(firstNode)
 
+	maxExpanded := f.maxExpanded
+	if maxExpanded == 0 && isUnbounded(m) {
+		maxExpanded = UnboundedMaxExpandedCount
+	}
+
 	for {
 		// no grid to search again, can't not find a path
 		if len(f.openList) == 0 {
@@ -189,15 +615,34 @@ func (f *BasePathFinder) FindPath(m NavigationMap, startGrid *Grid, dstGrid *Gri
 			break
 		}
 
-		f.impl.UnfoldGrid(m, dstGrid, node)
-		if f.lastNode != nil {
+		// the dest grid is found when it is popped, not when it is generated, so the
+		// path is the shortest one even if the costs of the steps are different
+		if dstGrid.IsSameGrid(node.GetGrid()) {
+			f.lastNode = node
+			break
+		}
+
+		// too many nodes, give up
+		if maxExpanded > 0 && f.stats.ExpandedCount >= maxExpanded {
 			break
 		}
+
+		f.stats.ExpandedCount++
+		if f.observer != nil {
+			f.observer.OnNodeExpanded(node)
+		}
+
+		f.impl.UnfoldGrid(m, dstGrid, node)
 	}
 
 	return f.getFullPath()
 }
 
+// find path for a kind of unit, the terrain decides where it can cross and the cost
+func (f *BasePathFinder) FindPathWithProfile(m TerrainMap, profile *MovementProfile, startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	return f.FindPath(NewProfileMap(m, profile), startGrid, dstGrid)
+}
+
 func (f *BasePathFinder) preCheck(m NavigationMap, startGrid *Grid, dstGrid *Grid) (fullPath []PathNode, bSucc bool, bFinish bool) {
 	// start grid can't cross
 	if !m.CanCross(startGrid.Col, startGrid.Row) {
@@ -209,6 +654,11 @@ func (f *BasePathFinder) preCheck(m NavigationMap, startGrid *Grid, dstGrid *Gri
 		return nil, false, true
 	}
 
+	// start grid and dest grid are in different regions
+	if f.componentIndex != nil && !f.componentIndex.IsReachable(startGrid, dstGrid) {
+		return nil, false, true
+	}
+
 	// start grid and dest grid is the same grid
 	if startGrid.IsSameGrid(dstGrid) {
 		node := f.impl.CreateFirstNode(startGrid.Col, startGrid.Row)
@@ -222,83 +672,321 @@ func (f *BasePathFinder) preCheck(m NavigationMap, startGrid *Grid, dstGrid *Gri
 	return nil, false, false
 }
 
+// the impl scanned the grid without adding a node for it
+func (f *BasePathFinder) AddScannedGrid(col int, row int) {
+	if !f.bScanned {
+		f.scanLeft, f.scanTop, f.scanRight, f.scanBottom = col, row, col, row
+		f.bScanned = true
+		return
+	}
+
+	f.scanLeft = minInt(f.scanLeft, col)
+	f.scanTop = minInt(f.scanTop, row)
+	f.scanRight = maxInt(f.scanRight, col)
+	f.scanBottom = maxInt(f.scanBottom, row)
+}
+
+// the bounds of the grids scanned by the last search, return false if no grid is scanned
+func (f *BasePathFinder) GetScannedBounds() (left int, top int, right int, bottom int, ok bool) {
+	return f.scanLeft, f.scanTop, f.scanRight, f.scanBottom, f.bScanned
+}
+
+// the nodes in open list and close list of the last search
+func (f *BasePathFinder) GetSearchedNodes() []PathNode {
+	nodes := make([]PathNode, 0, len(f.openList)+len(f.closeIndex))
+	for _, entry := range f.openList {
+		nodes = append(nodes, entry.node)
+	}
+
+	for _, node := range f.closeIndex {
+		nodes = append(nodes, node)
+	}
+
+	return nodes
+}
+
 func (f *BasePathFinder) AddNodeToOpenList(node PathNode) {
-	f.openList = append(f.openList, node)
+	f.stats.GeneratedCount++
+	f.pushOpenNode(node)
 }
 
-func (f *BasePathFinder) GetOpenNode(col int, row int) (PathNode, bool) {
-	for _, node := range f.openList {
-		grid := node.GetGrid()
-		if grid.IsSameGrid2(col, row) {
-			return node, true
-		}
+func (f *BasePathFinder) pushOpenNode(node PathNode) {
+	f.seq++
+	f.openList = append(f.openList, openEntry{
+		node:   node,
+		fValue: f.calF(node),
+		seq:    f.seq,
+	})
+
+	f.siftUp(len(f.openList) - 1)
+	f.openIndex[*node.GetGrid()] = node
+	if len(f.openList) > f.stats.PeakOpenCount {
+		f.stats.PeakOpenCount = len(f.openList)
 	}
 
-	return nil, false
+	if f.observer != nil {
+		f.observer.OnNodeOpened(node)
+	}
+}
+
+func (f *BasePathFinder) GetOpenNode(col int, row int) (PathNode, bool) {
+	node, ok := f.openIndex[Grid{Col: col, Row: row}]
+	return node, ok
 }
 
 func (f *BasePathFinder) AddNodeToCloseList(node PathNode) {
-	f.closeList = append(f.closeList, node)
+	f.closeIndex[*node.GetGrid()] = node
 }
 
 func (f *BasePathFinder) GetCloseNode(col int, row int) (PathNode, bool) {
-	for _, node := range f.closeList {
-		grid := node.GetGrid()
-		if grid.IsSameGrid2(col, row) {
-			return node, true
-		}
-	}
+	node, ok := f.closeIndex[Grid{Col: col, Row: row}]
+	return node, ok
+}
 
-	return nil, false
+func (f *BasePathFinder) RemoveNodeFromCloseList(node PathNode) {
+	grid := *node.GetGrid()
+	if f.closeIndex[grid] == node {
+		delete(f.closeIndex, grid)
+	}
 }
 
 func (f *BasePathFinder) UpdateExistList(m NavigationMap, col int, row int, parent PathNode, vecParent *Vector, minGValue uint32) bool {
+	return f.updateExistList(m, col, row, parent, vecParent, nil, minGValue)
+}
+
+// the same as UpdateExistList, but the node is reached by the link
+func (f *BasePathFinder) UpdateExistListByLink(m NavigationMap, parent PathNode, link *Link, minGValue uint32) bool {
+	return f.updateExistList(m, link.To.Col, link.To.Row, parent, VecStart, link, minGValue)
+}
+
+func (f *BasePathFinder) updateExistList(m NavigationMap, col int, row int, parent PathNode, vecParent *Vector, link *Link, minGValue uint32) bool {
 	exist, ok := f.GetOpenNode(col, row)
-	if !ok {
-		exist, ok = f.GetCloseNode(col, row)
+	if ok {
+		f.updateOpenNode(m, exist, parent, vecParent, link, minGValue)
+		return true
 	}
 
+	exist, ok = f.GetCloseNode(col, row)
 	if ok {
-		if exist.GetMinGValue() > minGValue {
-			exist.UpdateParent(parent, vecParent)
-			exist.SetMinGValue(minGValue, m)
+		f.updateCloseNode(m, exist, parent, vecParent, link, minGValue)
+		return true
+	}
+
+	return false
+}
+
+func (f *BasePathFinder) updateOpenNode(m NavigationMap, exist PathNode, parent PathNode, vecParent *Vector, link *Link, minGValue uint32) {
+	if !f.canRelink(exist, parent, minGValue) {
+		return
+	}
+
+	oldGValue := exist.GetMinGValue()
+	exist.UpdateParent(parent, vecParent)
+	setNodeLink(exist, link)
+	f.bOpenDirty = true
+	if f.updatePolicy == UpdatePolicyPropagate {
+		// a reopened node has children, the closed ones are unfolded again
+		exist.SetMinGValue(minGValue, m)
+		f.onNodeImproved(exist, oldGValue)
+		f.reopenTree(exist)
+		return
+	}
+
+	// the children are updated when it is unfolded
+	resetMinGValue(exist, minGValue, m)
+	f.onNodeImproved(exist, oldGValue)
+}
+
+func (f *BasePathFinder) updateCloseNode(m NavigationMap, exist PathNode, parent PathNode, vecParent *Vector, link *Link, minGValue uint32) {
+	if f.updatePolicy == UpdatePolicyIgnore {
+		return
+	}
+
+	if !f.canRelink(exist, parent, minGValue) {
+		return
+	}
+
+	oldGValue := exist.GetMinGValue()
+	exist.UpdateParent(parent, vecParent)
+	setNodeLink(exist, link)
+	if f.updatePolicy == UpdatePolicyPropagate {
+		// the children in open list may change
+		exist.SetMinGValue(minGValue, m)
+		f.bOpenDirty = true
+		f.onNodeImproved(exist, oldGValue)
+		f.reopenTree(exist)
+		return
+	}
+
+	// reopen, the children are updated when it is unfolded again
+	resetMinGValue(exist, minGValue, m)
+	f.onNodeImproved(exist, oldGValue)
+	if n, ok := exist.(reopenableNode); ok {
+		n.ResetUnfold()
+	}
+
+	f.RemoveNodeFromCloseList(exist)
+	f.pushOpenNode(exist)
+}
+
+func resetMinGValue(node PathNode, minGValue uint32, m NavigationMap) {
+	if n, ok := node.(resettableNode); ok {
+		n.ResetMinGValue(minGValue)
+	} else {
+		node.SetMinGValue(minGValue, m)
+	}
+}
+
+// the closed nodes of the tree have lower g values now, the neighbours out of the
+// tree may get lower g values through them, so unfold them again
+func (f *BasePathFinder) reopenTree(root PathNode) {
+	reopened := make([]PathNode, 0)
+	stack := []PathNode{root}
+	for len(stack) > 0 {
+		node, ok := stack[len(stack)-1].(parentNode)
+		stack = stack[:len(stack)-1]
+		if !ok {
+			continue
+		}
+
+		exist, ok := f.closeIndex[*node.getBaseNode().GetGrid()]
+		if ok && isSameNode(exist, node) {
+			reopened = append(reopened, exist)
 		}
+
+		stack = append(stack, node.GetChildren()...)
 	}
 
-	return ok
+	for _, node := range reopened {
+		delete(f.closeIndex, *node.GetGrid())
+		if n, ok := node.(reopenableNode); ok {
+			n.ResetUnfold()
+		}
+
+		f.pushOpenNode(node)
+	}
 }
 
-func (f *BasePathFinder) popMinValueNode(m NavigationMap, dstGrid *Grid) (PathNode, bool) {
-	minF := uint32(math.MaxUint32)
-	minIdx := -1
-
-	baseGValue := m.GetMinGValue()
-	// find index of the min F value node
-	for i, node := range f.openList {
-		h := f.calH(node, dstGrid, baseGValue)
-		f := node.GetMinGValue() + h
-		if minF > f {
-			minF = f
-			minIdx = i
+func isSameNode(node PathNode, other parentNode) bool {
+	n, ok := node.(parentNode)
+	return ok && n.getBaseNode() == other.getBaseNode()
+}
+
+// call it after the g value and the parent changed
+func (f *BasePathFinder) onNodeImproved(node PathNode, oldGValue uint32) {
+	f.stats.ImprovedCount++
+	if f.observer != nil {
+		f.observer.OnNodeImproved(node, oldGValue)
+	}
+}
+
+func (f *BasePathFinder) canRelink(exist PathNode, parent PathNode, minGValue uint32) bool {
+	if exist.GetMinGValue() <= minGValue {
+		return false
+	}
+
+	// the new parent is in the subtree of exist node, relink will make a loop
+	for node := parent; node != nil; node = node.GetParent() {
+		if node == exist {
+			return false
 		}
 	}
 
-	if minIdx == -1 {
+	return true
+}
+
+// the min F value node, the earlier one first if the F values are the same
+func (f *BasePathFinder) popMinValueNode(m NavigationMap, dstGrid *Grid) (PathNode, bool) {
+	if len(f.openList) == 0 {
 		return nil, false
 	}
 
+	// the g values changed, compute the F values again
+	if f.bOpenDirty {
+		for i := range f.openList {
+			f.openList[i].fValue = f.calF(f.openList[i].node)
+		}
+
+		for i := len(f.openList)/2 - 1; i >= 0; i-- {
+			f.siftDown(i)
+		}
+
+		f.bOpenDirty = false
+	}
+
 	// pop min F value node
-	minNode := f.openList[minIdx]
-	f.openList = append(f.openList[:minIdx], f.openList[minIdx+1:]...)
+	minNode := f.openList[0].node
+	last := len(f.openList) - 1
+	f.openList[0] = f.openList[last]
+	f.openList[last] = openEntry{}
+	f.openList = f.openList[:last]
+	if last > 0 {
+		f.siftDown(0)
+	}
+
+	grid := *minNode.GetGrid()
+	if f.openIndex[grid] == minNode {
+		delete(f.openIndex, grid)
+	}
 
 	return minNode, true
 }
 
-func (f *BasePathFinder) calH(node PathNode, dstGrid *Grid, baseGValue uint32) uint32 {
-	grid := node.GetGrid()
-	xAbs := math.Abs(float64(dstGrid.Col-grid.Col) * float64(baseGValue))
-	yAbs := math.Abs(float64(dstGrid.Row-grid.Row) * float64(baseGValue))
-	return uint32(xAbs + yAbs)
+func (f *BasePathFinder) calF(node PathNode) uint32 {
+	gValue := uint64(node.GetMinGValue())
+	hValue := uint64(f.calH(f.searchMap, node, &f.dstGrid, f.baseGValue))
+	if gValue+hValue > math.MaxUint32 {
+		return math.MaxUint32
+	}
+
+	return uint32(gValue + hValue)
+}
+
+func (f *BasePathFinder) isOpenLess(i int, j int) bool {
+	if f.openList[i].fValue != f.openList[j].fValue {
+		return f.openList[i].fValue < f.openList[j].fValue
+	}
+
+	return f.openList[i].seq < f.openList[j].seq
+}
+
+func (f *BasePathFinder) siftUp(i int) {
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !f.isOpenLess(i, parent) {
+			return
+		}
+
+		f.openList[i], f.openList[parent] = f.openList[parent], f.openList[i]
+		i = parent
+	}
+}
+
+func (f *BasePathFinder) siftDown(i int) {
+	n := len(f.openList)
+	for {
+		min := i
+		left := 2*i + 1
+		right := left + 1
+		if left < n && f.isOpenLess(left, min) {
+			min = left
+		}
+
+		if right < n && f.isOpenLess(right, min) {
+			min = right
+		}
+
+		if min == i {
+			return
+		}
+
+		f.openList[i], f.openList[min] = f.openList[min], f.openList[i]
+		i = min
+	}
+}
+
+func (f *BasePathFinder) calH(m NavigationMap, node PathNode, dstGrid *Grid, baseGValue uint32) uint32 {
+	return getHValue(m, node.GetGrid(), dstGrid, baseGValue, f.heuristic)
 }
 
 func (f *BasePathFinder) getFullPath() ([]PathNode, bool) {
@@ -306,7 +994,7 @@ func (f *BasePathFinder) getFullPath() ([]PathNode, bool) {
 		return nil, false
 	}
 
-	fullPath := make([]PathNode, 0)
+	fullPath := f.fullPath[:0]
 	node := f.lastNode
 	for {
 		if node == nil {
@@ -324,5 +1012,6 @@ func (f *BasePathFinder) getFullPath() ([]PathNode, bool) {
 		fullPath[i], fullPath[j] = fullPath[j], fullPath[i]
 	}
 
+	f.fullPath = fullPath
 	return fullPath, true
 }
@@ -0,0 +1,213 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"container/heap"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+//========================
+//     Dijkstra (test)
+//========================
+type dijkstraItem struct {
+	grid   Grid
+	gValue uint32
+}
+
+type dijkstraQueue []*dijkstraItem
+
+func (q dijkstraQueue) Len() int            { return len(q) }
+func (q dijkstraQueue) Less(i, j int) bool  { return q[i].gValue < q[j].gValue }
+func (q dijkstraQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *dijkstraQueue) Push(x interface{}) { *q = append(*q, x.(*dijkstraItem)) }
+func (q *dijkstraQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// the min g values from the grid to all the grids, or from all the grids to the grid if
+// bReverse, moving along the vectors costs the g value of the dest grid
+func getDijkstraGValues(m NavigationMap, grid *Grid, vectors []*Vector, bReverse bool) map[Grid]uint32 {
+	gValues := map[Grid]uint32{*grid: 0}
+	q := &dijkstraQueue{{grid: *grid}}
+	for q.Len() > 0 {
+		item := heap.Pop(q).(*dijkstraItem)
+		if item.gValue > gValues[item.grid] {
+			continue
+		}
+
+		for _, vec := range vectors {
+			next := Grid{Col: item.grid.Col + vec.X, Row: item.grid.Row + vec.Y}
+			if !m.CanCross(next.Col, next.Row) {
+				continue
+			}
+
+			// the reverse move goes from next to the grid of the item
+			addGValue := m.GetGValue(next.Col, next.Row)
+			if bReverse {
+				addGValue = m.GetGValue(item.grid.Col, item.grid.Row)
+			}
+
+			gValue := item.gValue + addGValue
+			old, ok := gValues[next]
+			if !ok || gValue < old {
+				gValues[next] = gValue
+				heap.Push(q, &dijkstraItem{grid: next, gValue: gValue})
+			}
+		}
+	}
+
+	return gValues
+}
+
+var orthogonalVectors = []*Vector{VecUp, VecRight, VecDown, VecLeft}
+
+//========================
+//     UpdatePolicy
+//========================
+// admissible but not consistent, so a closed grid gets a lower g value later
+//
+//	A C G      S -> A -> C -> G costs 11
+//	S B #      S -> B -> C -> G costs 13, C is closed by this path first
+func TestUpdatePolicyReopen(t *testing.T) {
+	m := NewEdgeLayer(newTestMap(t, "..9", "..#"))
+	m.SetEdge(NewGrid(1, 1), NewGrid(1, 0), 3, true)
+	gridA := NewGrid(0, 0)
+	heuristic := func(grid *Grid, dstGrid *Grid, baseGValue uint32) uint32 {
+		if grid.IsSameGrid(gridA) {
+			return 10
+		}
+
+		return 0
+	}
+
+	cases := []struct {
+		name   string
+		policy UpdatePolicy
+		want   uint32
+	}{
+		{"propagate", UpdatePolicyPropagate, 11},
+		{"reopen", UpdatePolicyReopen, 11},
+		// only safe with a consistent heuristic
+		{"ignore", UpdatePolicyIgnore, 13},
+	}
+
+	for _, c := range cases {
+		finder := NewAStar()
+		finder.SetUpdatePolicy(c.policy)
+		finder.SetHeuristic(heuristic)
+		gValue, ok := getPathGValue(t, finder, m, NewGrid(0, 1), NewGrid(2, 0))
+		if !ok || gValue != c.want {
+			t.Errorf("%s: got g value %d (%v), want %d", c.name, gValue, ok, c.want)
+		}
+
+		if c.policy != UpdatePolicyIgnore && finder.GetStats().ImprovedCount == 0 {
+			t.Errorf("%s: the closed grid is not improved", c.name)
+		}
+	}
+}
+
+// the heuristic is a random part of the real g value to the dest grid, it is admissible
+// but not consistent, so the closed grids are improved often
+func TestUpdatePolicyRandomHeuristic(t *testing.T) {
+	for seed := int64(0); seed < 50; seed++ {
+		m := newRandomTestMap(seed, 16, 16, 0.2, 9)
+		startGrid := NewGrid(0, 0)
+		dstGrid := NewGrid(15, 15)
+		if !m.CanCross(startGrid.Col, startGrid.Row) || !m.CanCross(dstGrid.Col, dstGrid.Row) {
+			continue
+		}
+
+		want, bReachable := getDijkstraGValues(m, startGrid, orthogonalVectors, false)[*dstGrid]
+		toDst := getDijkstraGValues(m, dstGrid, orthogonalVectors, true)
+
+		// the grids in a fixed order, so the heuristic of a seed is the same in each run
+		grids := make([]Grid, 0, len(toDst))
+		for grid := range toDst {
+			grids = append(grids, grid)
+		}
+
+		sort.Slice(grids, func(i int, j int) bool {
+			if grids[i].Row != grids[j].Row {
+				return grids[i].Row < grids[j].Row
+			}
+
+			return grids[i].Col < grids[j].Col
+		})
+
+		for hSeed := int64(0); hSeed < 20; hSeed++ {
+			rnd := rand.New(rand.NewSource(hSeed))
+			hValues := make(map[Grid]uint32)
+			for _, grid := range grids {
+				hValues[grid] = uint32(rnd.Int63n(int64(toDst[grid]) + 1))
+			}
+
+			heuristic := func(grid *Grid, dstGrid *Grid, baseGValue uint32) uint32 {
+				return hValues[*grid]
+			}
+
+			for _, policy := range []UpdatePolicy{UpdatePolicyPropagate, UpdatePolicyReopen} {
+				finder := NewAStar()
+				finder.SetUpdatePolicy(policy)
+				finder.SetHeuristic(heuristic)
+				gValue, ok := getPathGValue(t, finder, m, startGrid, dstGrid)
+				if ok != bReachable || gValue != want {
+					t.Errorf("seed %d/%d policy %d: got g value %d (%v), want %d (%v)", seed, hSeed, policy, gValue, ok, want, bReachable)
+				}
+			}
+		}
+	}
+}
+
+func getPathGrids(fullPath []PathNode) []Grid {
+	grids := make([]Grid, 0, len(fullPath))
+	for _, node := range fullPath {
+		grids = append(grids, *node.GetGrid())
+	}
+
+	return grids
+}
+
+func TestFindPathCopy(t *testing.T) {
+	m := newRandomTestMap(3, 32, 32, 0.2, 1)
+	for _, finder := range []PathFinder{NewAStar(), NewJps(0, false)} {
+		pathA, ok := finder.FindPath(m, NewGrid(0, 0), NewGrid(31, 31))
+		if !ok {
+			t.Fatal("no path a")
+		}
+
+		grids := getPathGrids(pathA)
+		gValue := pathA[len(pathA)-1].GetMinGValue()
+		if _, ok := finder.FindPath(m, NewGrid(31, 0), NewGrid(0, 31)); !ok {
+			t.Fatal("no path b")
+		}
+
+		if !reflect.DeepEqual(getPathGrids(pathA), grids) || pathA[len(pathA)-1].GetMinGValue() != gValue {
+			t.Error("path a is changed by search b")
+		}
+	}
+}
+
+func TestFindPathAllocs(t *testing.T) {
+	m := newRandomTestMap(3, 32, 32, 0.2, 1)
+	for _, finder := range []*BasePathFinder{NewAStar().BasePathFinder, NewJps(0, false).BasePathFinder} {
+		// the first search fills the pool
+		finder.SetPathReuse(true)
+		finder.FindPath(m, NewGrid(0, 0), NewGrid(31, 31))
+		allocs := testing.AllocsPerRun(10, func() {
+			finder.FindPath(m, NewGrid(0, 0), NewGrid(31, 31))
+		})
+
+		if allocs > 4 {
+			t.Errorf("got %v allocs per search, want at most 4", allocs)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"container/heap"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPathServiceClosed    = errors.New("path service: closed")
+	ErrPathDeadlineExceeded = errors.New("path service: deadline exceeded")
+)
+
+//========================
+//      PathRequest
+//========================
+type PathRequest struct {
+	StartGrid *Grid
+	DstGrid   *Grid
+	// the request with bigger priority is handled first
+	Priority int
+	// the request is dropped if not started before the deadline, zero means no deadline.
+	// a started search is not stopped by the deadline, bound it by the max expanded
+	// count of the finders, see BasePathFinder.SetMaxExpandedCount
+	Deadline time.Time
+}
+
+type PathResult struct {
+	FullPath []PathNode
+	Found    bool
+	Err      error
+}
+
+//========================
+//       PathFuture
+//========================
+// the result of a request, the identical requests share one future
+type PathFuture struct {
+	done   chan struct{}
+	result *PathResult
+}
+
+func newPathFuture() *PathFuture {
+	return &PathFuture{
+		done: make(chan struct{}),
+	}
+}
+
+// closed after the result is ready
+func (f *PathFuture) Done() <-chan struct{} {
+	return f.done
+}
+
+// block until the result is ready, the path is shared and should not be modified
+func (f *PathFuture) Wait() *PathResult {
+	<-f.done
+	return f.result
+}
+
+func (f *PathFuture) finish(result *PathResult) {
+	f.result = result
+	close(f.done)
+}
+
+//========================
+//      PathService
+//========================
+type pathJobKey struct {
+	startGrid Grid
+	dstGrid   Grid
+	snapshot  uint64
+}
+
+type pathJob struct {
+	key      pathJobKey
+	m        NavigationMap
+	priority int
+	deadline time.Time
+	seq      uint64
+	index    int
+	future   *PathFuture
+}
+
+// find paths by a pool of workers, each worker owns a finder, so the finders
+// are never shared between goroutines
+//
+// the map is a snapshot which must not be changed while the service uses it,
+// call SetMap with a new snapshot after the map changed, see NewGridMapFrom.
+// the lazy min g value of the map is computed before the workers read it
+type PathService struct {
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	m        NavigationMap
+	snapshot uint64
+	queue    pathJobHeap
+	inFlight map[pathJobKey]*pathJob
+	seq      uint64
+	bClosed  bool
+	wg       sync.WaitGroup
+}
+
+func NewPathService(m NavigationMap, newFinder func() PathFinder, workerCount int) *PathService {
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+
+	m.GetMinGValue()
+	s := &PathService{
+		m:        m,
+		inFlight: make(map[pathJobKey]*pathJob),
+	}
+
+	s.cond = sync.NewCond(&s.mutex)
+	for i := 0; i < workerCount; i++ {
+		s.wg.Add(1)
+		go s.work(newFinder())
+	}
+
+	return s
+}
+
+// the requests after it use the new snapshot, the requests before keep the old one
+func (s *PathService) SetMap(m NavigationMap) {
+	m.GetMinGValue()
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.m = m
+	s.snapshot++
+}
+
+func (s *PathService) GetMap() NavigationMap {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.m
+}
+
+// queue the request, the request identical to a waiting or running one gets the same future
+func (s *PathService) Submit(req *PathRequest) *PathFuture {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.bClosed {
+		future := newPathFuture()
+		future.finish(&PathResult{Err: ErrPathServiceClosed})
+		return future
+	}
+
+	key := pathJobKey{
+		startGrid: *req.StartGrid,
+		dstGrid:   *req.DstGrid,
+		snapshot:  s.snapshot,
+	}
+
+	job, ok := s.inFlight[key]
+	if ok {
+		s.mergeJob(job, req)
+		return job.future
+	}
+
+	s.seq++
+	job = &pathJob{
+		key:      key,
+		m:        s.m,
+		priority: req.Priority,
+		deadline: req.Deadline,
+		seq:      s.seq,
+		future:   newPathFuture(),
+	}
+
+	s.inFlight[key] = job
+	heap.Push(&s.queue, job)
+	s.cond.Signal()
+	return job.future
+}
+
+// queue the request and send the result to the channel when it is ready
+func (s *PathService) SubmitToChan(req *PathRequest, ch chan<- *PathResult) {
+	future := s.Submit(req)
+	go func() {
+		ch <- future.Wait()
+	}()
+}
+
+// find the path and wait for the result
+func (s *PathService) FindPath(startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	result := s.Submit(&PathRequest{StartGrid: startGrid, DstGrid: dstGrid}).Wait()
+	return result.FullPath, result.Found
+}
+
+// stop the workers after the running requests finished, the waiting requests fail
+func (s *PathService) Close() {
+	s.mutex.Lock()
+	if s.bClosed {
+		s.mutex.Unlock()
+		return
+	}
+
+	s.bClosed = true
+	for s.queue.Len() > 0 {
+		job := heap.Pop(&s.queue).(*pathJob)
+		delete(s.inFlight, job.key)
+		job.future.finish(&PathResult{Err: ErrPathServiceClosed})
+	}
+
+	s.cond.Broadcast()
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
+
+// the shared job takes the higher priority and the later deadline
+func (s *PathService) mergeJob(job *pathJob, req *PathRequest) {
+	if !job.deadline.IsZero() && (req.Deadline.IsZero() || req.Deadline.After(job.deadline)) {
+		job.deadline = req.Deadline
+	}
+
+	// already running
+	if job.index < 0 {
+		return
+	}
+
+	if req.Priority > job.priority {
+		job.priority = req.Priority
+		heap.Fix(&s.queue, job.index)
+	}
+}
+
+func (s *PathService) work(finder PathFinder) {
+	defer s.wg.Done()
+
+	for {
+		job, deadline, ok := s.popJob()
+		if !ok {
+			return
+		}
+
+		result := &PathResult{}
+		if !deadline.IsZero() && time.Now().After(deadline) {
+			result.Err = ErrPathDeadlineExceeded
+		} else {
+			// the nodes of the finder are reused by the next search
+			fullPath, bFound := finder.FindPath(job.m, &job.key.startGrid, &job.key.dstGrid)
+			result.FullPath = ClonePath(fullPath)
+			result.Found = bFound
+		}
+
+		s.mutex.Lock()
+		delete(s.inFlight, job.key)
+		s.mutex.Unlock()
+		job.future.finish(result)
+	}
+}
+
+// the deadline may be changed by the identical requests, so read it in lock
+func (s *PathService) popJob() (*pathJob, time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for s.queue.Len() == 0 {
+		if s.bClosed {
+			return nil, time.Time{}, false
+		}
+
+		s.cond.Wait()
+	}
+
+	job := heap.Pop(&s.queue).(*pathJob)
+	return job, job.deadline, true
+}
+
+//========================
+//      pathJobHeap
+//========================
+type pathJobHeap []*pathJob
+
+func (h pathJobHeap) Len() int {
+	return len(h)
+}
+
+// higher priority first, then the earlier one
+func (h pathJobHeap) Less(i int, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+
+	return h[i].seq < h[j].seq
+}
+
+func (h pathJobHeap) Swap(i int, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *pathJobHeap) Push(x interface{}) {
+	job := x.(*pathJob)
+	job.index = len(*h)
+	*h = append(*h, job)
+}
+
+func (h *pathJobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	job := old[n-1]
+	job.index = -1
+	*h = old[:n-1]
+	return job
+}
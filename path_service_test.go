@@ -0,0 +1,208 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+)
+
+// the searches wait for the gate, the destinations are sent to started in order
+type gateFinder struct {
+	*AStar
+	started chan Grid
+	gate    chan struct{}
+}
+
+func newGateFinder() *gateFinder {
+	return &gateFinder{
+		AStar:   NewAStar(),
+		started: make(chan Grid, 16),
+		gate:    make(chan struct{}),
+	}
+}
+
+func (f *gateFinder) FindPath(m NavigationMap, startGrid *Grid, dstGrid *Grid) ([]PathNode, bool) {
+	f.started <- *dstGrid
+	<-f.gate
+	return f.AStar.FindPath(m, startGrid, dstGrid)
+}
+
+// the min g value of the loaded map is lazy
+func newLoadedTestMap(t *testing.T, src *GridMap) *GridMap {
+	data, err := src.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := &GridMap{}
+	if err := m.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+
+	return m
+}
+
+func TestPathServiceConcurrent(t *testing.T) {
+	src := newRandomTestMap(5, 32, 32, 0.2, 3)
+	rnd := rand.New(rand.NewSource(5))
+	grids := make([]*Grid, 0)
+	for len(grids) < 16 {
+		col, row := rnd.Intn(32), rnd.Intn(32)
+		if src.CanCross(col, row) {
+			grids = append(grids, NewGrid(col, row))
+		}
+	}
+
+	finder := NewAStar()
+	want := make(map[[2]int]uint32)
+	for i := range grids {
+		for j := range grids {
+			fullPath, ok := finder.FindPath(src, grids[i], grids[j])
+			if ok {
+				want[[2]int{i, j}] = fullPath[len(fullPath)-1].GetMinGValue()
+			}
+		}
+	}
+
+	snapshots := make([]*GridMap, 0)
+	for k := 0; k < 8; k++ {
+		snapshots = append(snapshots, newLoadedTestMap(t, src))
+	}
+
+	s := NewPathService(newLoadedTestMap(t, src), func() PathFinder { return NewAStar() }, 4)
+	wg := sync.WaitGroup{}
+	for k := 0; k < 8; k++ {
+		wg.Add(1)
+		go func(k int) {
+			defer wg.Done()
+			for n := 0; n < 64; n++ {
+				i, j := (k*7+n)%len(grids), (k+n*5)%len(grids)
+				// the same map in a new snapshot
+				if n == 32 {
+					s.SetMap(snapshots[k])
+				}
+
+				result := s.Submit(&PathRequest{StartGrid: grids[i], DstGrid: grids[j], Priority: n % 3}).Wait()
+				gValue, ok := want[[2]int{i, j}]
+				if result.Err != nil || result.Found != ok {
+					t.Errorf("%v -> %v: got %v (%v), want %v", grids[i], grids[j], result.Found, result.Err, ok)
+					continue
+				}
+
+				if ok && result.FullPath[len(result.FullPath)-1].GetMinGValue() != gValue {
+					t.Errorf("%v -> %v: got g value %d, want %d", grids[i], grids[j], result.FullPath[len(result.FullPath)-1].GetMinGValue(), gValue)
+				}
+			}
+		}(k)
+	}
+
+	wg.Wait()
+	s.Close()
+}
+
+// the workers start the searches on the loaded map at once
+func TestPathServiceLoadedMap(t *testing.T) {
+	gate := newGateFinder()
+	newFinder := func() PathFinder {
+		return &gateFinder{AStar: NewAStar(), started: gate.started, gate: gate.gate}
+	}
+
+	s := NewPathService(newLoadedTestMap(t, newTestMap(t, "....", "....")), newFinder, 4)
+	defer s.Close()
+
+	futures := make([]*PathFuture, 0)
+	for col := 0; col < 4; col++ {
+		futures = append(futures, s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(col, 1)}))
+	}
+
+	for range futures {
+		<-gate.started
+	}
+
+	close(gate.gate)
+	for _, future := range futures {
+		if result := future.Wait(); !result.Found {
+			t.Error("no path")
+		}
+	}
+}
+
+func TestPathServiceOrder(t *testing.T) {
+	m := newTestMap(t, "....", "....")
+	finder := newGateFinder()
+	s := NewPathService(m, func() PathFinder { return finder }, 1)
+	defer s.Close()
+
+	running := s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(0, 1)})
+	<-finder.started
+
+	low := s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(1, 1), Priority: -1})
+	high := s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(2, 1), Priority: 1})
+	shared := s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(3, 1)})
+	expired := s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(3, 0), Priority: 3, Deadline: time.Now().Add(-time.Second)})
+	// the identical request shares the future and raises the priority
+	if s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(3, 1), Priority: 2}) != shared {
+		t.Error("the identical requests don't share the future")
+	}
+
+	close(finder.gate)
+	for _, future := range []*PathFuture{running, shared, high, low} {
+		if result := future.Wait(); !result.Found || result.Err != nil {
+			t.Errorf("got %v (%v), want a path", result.Found, result.Err)
+		}
+	}
+
+	if result := expired.Wait(); result.Err != ErrPathDeadlineExceeded {
+		t.Errorf("got error %v, want %v", result.Err, ErrPathDeadlineExceeded)
+	}
+
+	// the expired request is dropped without a search
+	want := []Grid{{Col: 3, Row: 1}, {Col: 2, Row: 1}, {Col: 1, Row: 1}}
+	for _, dst := range want {
+		if got := <-finder.started; got != dst {
+			t.Errorf("got search to %v, want %v", got, dst)
+		}
+	}
+}
+
+func TestPathServiceClose(t *testing.T) {
+	m := newTestMap(t, "....", "....")
+	finder := newGateFinder()
+	s := NewPathService(m, func() PathFinder { return finder }, 1)
+
+	running := s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(3, 1)})
+	<-finder.started
+	waiting := s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(3, 0)})
+
+	closed := make(chan struct{})
+	go func() {
+		s.Close()
+		close(closed)
+	}()
+
+	// the waiting request fails at once, Close waits for the running one
+	if result := waiting.Wait(); result.Err != ErrPathServiceClosed {
+		t.Errorf("waiting: got error %v, want %v", result.Err, ErrPathServiceClosed)
+	}
+
+	select {
+	case <-closed:
+		t.Error("closed before the running request finished")
+	default:
+	}
+
+	close(finder.gate)
+	<-closed
+	if result := running.Wait(); !result.Found || result.Err != nil {
+		t.Errorf("running: got %v (%v), want a path", result.Found, result.Err)
+	}
+
+	if result := s.Submit(&PathRequest{StartGrid: NewGrid(0, 0), DstGrid: NewGrid(1, 0)}).Wait(); result.Err != ErrPathServiceClosed {
+		t.Errorf("after close: got error %v, want %v", result.Err, ErrPathServiceClosed)
+	}
+}
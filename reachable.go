@@ -0,0 +1,174 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "container/heap"
+
+//========================
+//     ReachableArea
+//========================
+// the grids can be reached within a budget, with the min cost and the parent of each grid
+type ReachableArea struct {
+	startGrid *Grid
+	budget    uint32
+	costs     map[Grid]uint32
+	parents   map[Grid]Grid
+}
+
+// all the grids can be reached from the start grid with the g value not greater than the budget,
+// the moves follow the connectivity and the links of the map
+func ReachableWithin(m NavigationMap, startGrid *Grid, budget uint32, connectivity Connectivity) *ReachableArea {
+	a := &ReachableArea{
+		startGrid: NewGrid(startGrid.Col, startGrid.Row),
+		budget:    budget,
+		costs:     make(map[Grid]uint32),
+		parents:   make(map[Grid]Grid),
+	}
+
+	if !m.CanCross(startGrid.Col, startGrid.Row) {
+		return a
+	}
+
+	a.costs[*startGrid] = 0
+	open := &gridCostHeap{}
+	heap.Push(open, gridCostItem{grid: *startGrid, cost: 0})
+	for open.Len() > 0 {
+		item := heap.Pop(open).(gridCostItem)
+		if item.cost != a.costs[item.grid] {
+			continue
+		}
+
+		grid := item.grid
+		for _, vec := range getMoveVectors(connectivity) {
+			addGValue, ok := getMoveGValue(m, connectivity, grid.Col, grid.Row, vec)
+			if ok {
+				a.relax(open, grid, Grid{Col: grid.Col + vec.X, Row: grid.Row + vec.Y}, item.cost, addGValue)
+			}
+		}
+
+		for _, link := range getLinks(m, grid.Col, grid.Row) {
+			if m.CanCross(link.To.Col, link.To.Row) {
+				a.relax(open, grid, link.To, item.cost, link.GValue)
+			}
+		}
+	}
+
+	return a
+}
+
+func (a *ReachableArea) GetStartGrid() *Grid {
+	return a.startGrid
+}
+
+func (a *ReachableArea) GetBudget() uint32 {
+	return a.budget
+}
+
+// the min cost from the start grid, return false if can't reach
+func (a *ReachableArea) GetCost(col int, row int) (uint32, bool) {
+	cost, ok := a.costs[Grid{Col: col, Row: row}]
+	return cost, ok
+}
+
+func (a *ReachableArea) IsReachable(col int, row int) bool {
+	_, ok := a.costs[Grid{Col: col, Row: row}]
+	return ok
+}
+
+// the previous grid on the min cost path, return false for the start grid or the grid can't reach
+func (a *ReachableArea) GetParent(col int, row int) (*Grid, bool) {
+	parent, ok := a.parents[Grid{Col: col, Row: row}]
+	if !ok {
+		return nil, false
+	}
+
+	return NewGrid(parent.Col, parent.Row), true
+}
+
+// all the reachable grids, include the start grid
+func (a *ReachableArea) GetGrids() []*Grid {
+	grids := make([]*Grid, 0, len(a.costs))
+	for grid := range a.costs {
+		grids = append(grids, NewGrid(grid.Col, grid.Row))
+	}
+
+	return grids
+}
+
+// the path from the start grid to the grid
+func (a *ReachableArea) GetPath(col int, row int) ([]*Grid, bool) {
+	if !a.IsReachable(col, row) {
+		return nil, false
+	}
+
+	path := []*Grid{NewGrid(col, row)}
+	for {
+		parent, ok := a.GetParent(col, row)
+		if !ok {
+			break
+		}
+
+		path = append(path, parent)
+		col = parent.Col
+		row = parent.Row
+	}
+
+	// reverse
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path, true
+}
+
+func (a *ReachableArea) relax(open *gridCostHeap, from Grid, to Grid, cost uint32, addGValue uint32) {
+	newCost := uint64(cost) + uint64(addGValue)
+	if newCost > uint64(a.budget) {
+		return
+	}
+
+	oldCost, ok := a.costs[to]
+	if ok && uint64(oldCost) <= newCost {
+		return
+	}
+
+	a.costs[to] = uint32(newCost)
+	a.parents[to] = from
+	heap.Push(open, gridCostItem{grid: to, cost: uint32(newCost)})
+}
+
+//========================
+//      gridCostHeap
+//========================
+type gridCostItem struct {
+	grid Grid
+	cost uint32
+}
+
+type gridCostHeap []gridCostItem
+
+func (h gridCostHeap) Len() int {
+	return len(h)
+}
+
+func (h gridCostHeap) Less(i int, j int) bool {
+	return h[i].cost < h[j].cost
+}
+
+func (h gridCostHeap) Swap(i int, j int) {
+	h[i], h[j] = h[j], h[i]
+}
+
+func (h *gridCostHeap) Push(x interface{}) {
+	*h = append(*h, x.(gridCostItem))
+}
+
+func (h *gridCostHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
@@ -0,0 +1,77 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// the costs of the area are the g values of the finder with the same move rules
+func TestReachableWithinFinders(t *testing.T) {
+	cases := []struct {
+		name         string
+		connectivity Connectivity
+		finder       PathFinder
+	}{
+		{"astar", Connectivity4, NewAStar()},
+		{"jps", Connectivity8, NewJps(0, false)},
+		{"jps8", Connectivity8CornerCut, NewJps(0, true)},
+	}
+
+	for _, c := range cases {
+		for seed := int64(0); seed < 20; seed++ {
+			m := newRandomTestMap(seed, 16, 16, 0.25, 5)
+			rnd := rand.New(rand.NewSource(seed))
+			startGrid := NewGrid(rnd.Intn(16), rnd.Intn(16))
+			area := ReachableWithin(m, startGrid, math.MaxUint32, c.connectivity)
+			for i := 0; i < 16; i++ {
+				dstGrid := NewGrid(rnd.Intn(16), rnd.Intn(16))
+				if !m.CanCross(startGrid.Col, startGrid.Row) || !m.CanCross(dstGrid.Col, dstGrid.Row) {
+					continue
+				}
+
+				want, bReachable := getPathGValue(t, c.finder, m, startGrid, dstGrid)
+				cost, ok := area.GetCost(dstGrid.Col, dstGrid.Row)
+				if ok != bReachable || cost != want {
+					t.Errorf("%s seed %d %v -> %v: got cost %d (%v), want %d (%v)",
+						c.name, seed, startGrid, dstGrid, cost, ok, want, bReachable)
+				}
+			}
+		}
+	}
+}
+
+//	. 9 .
+//	. # .
+//	S . .
+func TestReachableWithinObliqueCost(t *testing.T) {
+	m := newTestMap(t, ".9.", ".#.", "...")
+	startGrid := NewGrid(0, 2)
+	cases := []struct {
+		connectivity Connectivity
+		grid         *Grid
+		want         uint32
+		bReachable   bool
+	}{
+		{Connectivity8, NewGrid(1, 1), 0, false},
+		// (1, 2), then through the side grid (2, 2)
+		{Connectivity8, NewGrid(2, 1), 3, true},
+		// (0, 1), then through the side grid (0, 0) as (1, 1) can't be crossed
+		{Connectivity8, NewGrid(1, 0), 11, true},
+		// (0, 1), then (1, 0) directly
+		{Connectivity8CornerCut, NewGrid(1, 0), 10, true},
+		{Connectivity8CornerCut, NewGrid(2, 1), 2, true},
+	}
+
+	for _, c := range cases {
+		area := ReachableWithin(m, startGrid, 20, c.connectivity)
+		cost, ok := area.GetCost(c.grid.Col, c.grid.Row)
+		if ok != c.bReachable || cost != c.want {
+			t.Errorf("connectivity %d %v: got cost %d (%v), want %d (%v)", c.connectivity, c.grid, cost, ok, c.want, c.bReachable)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+// the max count of the local repairs for a path, then the whole path is searched again
+const maxLocalRepairCount = 32
+
+//========================
+//       RectMap
+//========================
+// only the grids in the rect can be crossed, bound the search of a finder
+type RectMap struct {
+	NavigationMap
+	mapForwarder
+	left   int
+	top    int
+	right  int
+	bottom int
+}
+
+func NewRectMap(m NavigationMap, left int, top int, right int, bottom int) *RectMap {
+	return &RectMap{
+		NavigationMap: m,
+		mapForwarder:  mapForwarder{inner: m},
+		left:          left,
+		top:           top,
+		right:         right,
+		bottom:        bottom,
+	}
+}
+
+func (m *RectMap) CanCross(col int, row int) bool {
+	if col < m.left || row < m.top || col > m.right || row > m.bottom {
+		return false
+	}
+
+	return m.NavigationMap.CanCross(col, row)
+}
+
+func (m *RectMap) GetEdgeGValue(fromCol int, fromRow int, toCol int, toRow int) (uint32, bool) {
+	if !m.CanCross(toCol, toRow) {
+		return 0, false
+	}
+
+	return getEdgeGValue(m.NavigationMap, fromCol, fromRow, toCol, toRow)
+}
+
+//========================
+//       RepairPath
+//========================
+// a grid of the path, seg is the index of the path grid it goes to, bLink is true if
+// it is reached by a link from the former one
+type pathCell struct {
+	grid  Grid
+	seg   int
+	bLink bool
+}
+
+// the finder tells if its oblique move cuts the corners
+type obliqueMoveFinder interface {
+	CanObliqueMove() bool
+}
+
+// the index of the first path grid whose segment to the next one is blocked, the grids
+// between two path grids are on the straight line (or the diagonal) of them, unless a
+// link goes from one to the other. the moves are checked by the rules of the finder
+func FindInvalidSegment(finder PathFinder, m NavigationMap, path GridPath) (int, bool) {
+	cells := expandPath(m, path)
+	idx := findInvalidCell(finder, m, cells)
+	if idx < 0 {
+		return 0, false
+	}
+
+	return maxInt(cells[idx].seg-1, 0), true
+}
+
+// fix the path after the map changed. the part from the grid before the first blocked one to
+// a later crossable grid of the path is replaced by a local search bounded in their rect
+// expanded by the margin, only if it fails the whole path is searched again
+func RepairPath(finder PathFinder, m NavigationMap, path GridPath, margin int) (GridPath, bool) {
+	if len(path) == 0 {
+		return path, false
+	}
+
+	for i := 0; i < maxLocalRepairCount; i++ {
+		cells := expandPath(m, path)
+		idx := findInvalidCell(finder, m, cells)
+		if idx < 0 {
+			return path, true
+		}
+
+		// the start grid is blocked
+		if idx == 0 {
+			return nil, false
+		}
+
+		repaired, ok := repairLocal(finder, m, path, cells, idx, margin)
+		if !ok {
+			break
+		}
+
+		path = repaired
+	}
+
+	fullPath, ok := finder.FindPath(m, &path[0], &path[len(path)-1])
+	if !ok {
+		return nil, false
+	}
+
+	return NewGridPath(fullPath), true
+}
+
+func expandPath(m NavigationMap, path GridPath) []*pathCell {
+	cells := make([]*pathCell, 0, len(path))
+	if len(path) == 0 {
+		return cells
+	}
+
+	cells = append(cells, &pathCell{grid: path[0], seg: 0})
+	for i := 1; i < len(path); i++ {
+		grid := path[i-1]
+		if hasLink(m, &grid, &path[i]) {
+			cells = append(cells, &pathCell{grid: path[i], seg: i, bLink: true})
+			continue
+		}
+
+		for !grid.IsSameGrid(&path[i]) {
+			grid.Col += signInt(path[i].Col - grid.Col)
+			grid.Row += signInt(path[i].Row - grid.Row)
+			cells = append(cells, &pathCell{grid: grid, seg: i})
+		}
+	}
+
+	return cells
+}
+
+// the index of the first cell can't be crossed or can't be moved to from the former one, or -1
+func findInvalidCell(finder PathFinder, m NavigationMap, cells []*pathCell) int {
+	for i, cell := range cells {
+		if i == 0 {
+			if !m.CanCross(cell.grid.Col, cell.grid.Row) {
+				return 0
+			}
+
+			continue
+		}
+
+		if cell.bLink {
+			if !m.CanCross(cell.grid.Col, cell.grid.Row) {
+				return i
+			}
+
+			continue
+		}
+
+		if !canMoveTo(finder, m, &cells[i-1].grid, &cell.grid) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+func hasLink(m NavigationMap, from *Grid, to *Grid) bool {
+	for _, link := range getLinks(m, from.Col, from.Row) {
+		if link.To.IsSameGrid(to) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// move to the adjacent grid, an oblique move goes through a side grid like Jps,
+// unless the finder cuts the corners
+func canMoveTo(finder PathFinder, m NavigationMap, from *Grid, to *Grid) bool {
+	if from.Col == to.Col || from.Row == to.Row {
+		_, ok := getEdgeGValue(m, from.Col, from.Row, to.Col, to.Row)
+		return ok
+	}
+
+	obliqueFinder, ok := finder.(obliqueMoveFinder)
+	if ok && obliqueFinder.CanObliqueMove() {
+		_, ok = getEdgeGValue(m, from.Col, from.Row, to.Col, to.Row)
+		return ok
+	}
+
+	_, ok = getObliqueGValueBySide(m, from.Col, from.Row, to.Col, to.Row)
+	return ok
+}
+
+// search from the cell before the invalid one to the first crossable cell after it, and
+// splice the local path into the path
+func repairLocal(finder PathFinder, m NavigationMap, path GridPath, cells []*pathCell, invalidIdx int, margin int) (GridPath, bool) {
+	rejoinIdx := -1
+	for i := invalidIdx; i < len(cells); i++ {
+		if m.CanCross(cells[i].grid.Col, cells[i].grid.Row) {
+			rejoinIdx = i
+			break
+		}
+	}
+
+	if rejoinIdx < 0 {
+		return nil, false
+	}
+
+	from := cells[invalidIdx-1]
+	to := cells[rejoinIdx]
+	rectMap := NewRectMap(m,
+		minInt(from.grid.Col, to.grid.Col)-margin,
+		minInt(from.grid.Row, to.grid.Row)-margin,
+		maxInt(from.grid.Col, to.grid.Col)+margin,
+		maxInt(from.grid.Row, to.grid.Row)+margin)
+
+	fullPath, ok := finder.FindPath(rectMap, &from.grid, &to.grid)
+	if !ok {
+		return nil, false
+	}
+
+	// the path grids before the segment of the local start, then the local path, then
+	// the path grids from the segment of the rejoin grid
+	repaired := make(GridPath, 0, len(path)+len(fullPath))
+	repaired = append(repaired, path[:from.seg]...)
+	repaired = append(repaired, NewGridPath(fullPath)...)
+	if !to.grid.IsSameGrid(&path[to.seg]) {
+		repaired = append(repaired, path[to.seg])
+	}
+
+	repaired = append(repaired, path[to.seg+1:]...)
+	return repaired, true
+}
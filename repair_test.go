@@ -0,0 +1,51 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "testing"
+
+func TestFindInvalidSegment(t *testing.T) {
+	corner := newTestMap(t, ".#.", "#..")
+	links := NewLinkLayer(newTestMap(t, "..#.."))
+	links.AddLink(NewGrid(1, 0), NewGrid(3, 0), 5, LinkTypeTeleport, false)
+
+	cases := []struct {
+		name     string
+		finder   PathFinder
+		m        NavigationMap
+		path     GridPath
+		bInvalid bool
+	}{
+		// the oblique move squeezes between the two walls
+		{"jps corner", NewJps(0, false), corner, GridPath{{Col: 0, Row: 0}, {Col: 1, Row: 1}, {Col: 2, Row: 1}}, true},
+		{"jps8 corner", NewJps(0, true), corner, GridPath{{Col: 0, Row: 0}, {Col: 1, Row: 1}, {Col: 2, Row: 1}}, false},
+		{"link", NewAStar(), links, GridPath{{Col: 0, Row: 0}, {Col: 1, Row: 0}, {Col: 3, Row: 0}, {Col: 4, Row: 0}}, false},
+		{"no link", NewAStar(), links, GridPath{{Col: 0, Row: 0}, {Col: 4, Row: 0}}, true},
+	}
+
+	for _, c := range cases {
+		idx, bInvalid := FindInvalidSegment(c.finder, c.m, c.path)
+		if bInvalid != c.bInvalid || idx != 0 {
+			t.Errorf("%s: got segment %d (%v), want 0 (%v)", c.name, idx, bInvalid, c.bInvalid)
+		}
+	}
+}
+
+func TestRepairPathCorner(t *testing.T) {
+	m := newTestMap(t, "...", "...", "...")
+	m.SetCanCross(1, 0, false)
+	m.SetCanCross(0, 1, false)
+	path := GridPath{{Col: 0, Row: 0}, {Col: 2, Row: 2}}
+
+	// jps can't squeeze between the walls, so the start grid is walled in
+	if _, ok := RepairPath(NewJps(0, false), m, path, 2); ok {
+		t.Error("jps: the path is repaired through the corner")
+	}
+
+	repaired, ok := RepairPath(NewJps(0, true), m, path, 2)
+	if !ok || len(repaired) != 2 {
+		t.Errorf("jps8: got path %v (%v), want the path unchanged", repaired, ok)
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "math"
+
+type TerrainType uint8
+
+const MaxTerrainTypeCount = math.MaxUint8 + 1
+
+//========================
+//      TerrainMap
+//========================
+type TerrainMap interface {
+	NavigationMap
+	GetTerrain(col int, row int) TerrainType
+}
+
+//========================
+//      TerrainLayer
+//========================
+// add a terrain type for each grid of a navigation map, the terrains of an
+// unbounded map are kept only for the grids set
+type TerrainLayer struct {
+	NavigationMap
+	mapForwarder
+	cols       int
+	rows       int
+	terrains   []TerrainType
+	terrainMap map[Grid]TerrainType
+}
+
+func NewTerrainLayer(m NavigationMap) *TerrainLayer {
+	if isUnbounded(m) {
+		return &TerrainLayer{
+			NavigationMap: m,
+			mapForwarder:  mapForwarder{inner: m},
+			terrainMap:    make(map[Grid]TerrainType),
+		}
+	}
+
+	cols, rows := m.GetColRow()
+	return &TerrainLayer{
+		NavigationMap: m,
+		mapForwarder:  mapForwarder{inner: m},
+		cols:          int(cols),
+		rows:          int(rows),
+		terrains:      make([]TerrainType, int(cols)*int(rows)),
+	}
+}
+
+func (l *TerrainLayer) SetTerrain(col int, row int, terrain TerrainType) {
+	if l.terrainMap != nil {
+		l.terrainMap[Grid{Col: col, Row: row}] = terrain
+		return
+	}
+
+	if !l.isInMap(col, row) {
+		return
+	}
+
+	l.terrains[row*l.cols+col] = terrain
+}
+
+func (l *TerrainLayer) GetTerrain(col int, row int) TerrainType {
+	if l.terrainMap != nil {
+		return l.terrainMap[Grid{Col: col, Row: row}]
+	}
+
+	if !l.isInMap(col, row) {
+		return 0
+	}
+
+	return l.terrains[row*l.cols+col]
+}
+
+func (l *TerrainLayer) isInMap(col int, row int) bool {
+	return col >= 0 && row >= 0 && col < l.cols && row < l.rows
+}
+
+//========================
+//    MovementProfile
+//========================
+type TerrainRule struct {
+	bCanCross   bool
+	costPercent uint32
+}
+
+// how a kind of unit moves on each terrain, the terrain not set can't be crossed
+type MovementProfile struct {
+	name           string
+	rules          [MaxTerrainTypeCount]TerrainRule
+	minCostPercent uint32
+}
+
+func NewMovementProfile(name string) *MovementProfile {
+	return &MovementProfile{
+		name:           name,
+		minCostPercent: 100,
+	}
+}
+
+func (p *MovementProfile) GetName() string {
+	return p.name
+}
+
+// costPercent is the percent of the g value of the grid, 100 means no change
+func (p *MovementProfile) SetTerrain(terrain TerrainType, bCanCross bool, costPercent uint32) {
+	p.rules[terrain] = TerrainRule{
+		bCanCross:   bCanCross,
+		costPercent: costPercent,
+	}
+
+	p.updateMinCostPercent()
+}
+
+func (p *MovementProfile) CanCross(terrain TerrainType) bool {
+	return p.rules[terrain].bCanCross
+}
+
+func (p *MovementProfile) GetGValue(terrain TerrainType, gValue uint32) uint32 {
+	costPercent := uint64(p.rules[terrain].costPercent)
+	return uint32((uint64(gValue)*costPercent + 99) / 100)
+}
+
+func (p *MovementProfile) GetMinCostPercent() uint32 {
+	return p.minCostPercent
+}
+
+func (p *MovementProfile) updateMinCostPercent() {
+	minCostPercent := uint32(math.MaxUint32)
+	for _, rule := range p.rules {
+		if rule.bCanCross && rule.costPercent < minCostPercent {
+			minCostPercent = rule.costPercent
+		}
+	}
+
+	if minCostPercent == math.MaxUint32 {
+		minCostPercent = 100
+	}
+
+	p.minCostPercent = minCostPercent
+}
+
+//========================
+//      ProfileMap
+//========================
+// a view of a terrain map for the movement profile, the terrain decides
+// whether a grid can be crossed instead of the map
+type ProfileMap struct {
+	TerrainMap
+	mapForwarder
+	profile *MovementProfile
+}
+
+func NewProfileMap(m TerrainMap, profile *MovementProfile) *ProfileMap {
+	return &ProfileMap{
+		TerrainMap:   m,
+		mapForwarder: mapForwarder{inner: m},
+		profile:      profile,
+	}
+}
+
+func (m *ProfileMap) GetProfile() *MovementProfile {
+	return m.profile
+}
+
+func (m *ProfileMap) CanCross(col int, row int) bool {
+	if !isInMapBounds(m, col, row) {
+		return false
+	}
+
+	return m.profile.CanCross(m.GetTerrain(col, row))
+}
+
+func (m *ProfileMap) GetGValue(col int, row int) uint32 {
+	return m.profile.GetGValue(m.GetTerrain(col, row), m.TerrainMap.GetGValue(col, row))
+}
+
+// the edge g value of the inner map changed by the terrain of the dest grid, the inner
+// map can't decide whether a grid can be crossed, so only the edges set are used
+func (m *ProfileMap) GetEdgeGValue(fromCol int, fromRow int, toCol int, toRow int) (uint32, bool) {
+	if !m.CanCross(toCol, toRow) {
+		return 0, false
+	}
+
+	if !hasEdgeCost(m.TerrainMap, fromCol, fromRow) && !hasEdgeCost(m.TerrainMap, toCol, toRow) {
+		return m.GetGValue(toCol, toRow), true
+	}
+
+	gValue, ok := getEdgeGValue(m.TerrainMap, fromCol, fromRow, toCol, toRow)
+	if !ok {
+		return 0, false
+	}
+
+	return m.profile.GetGValue(m.GetTerrain(toCol, toRow), gValue), true
+}
+
+// keep the heuristic admissible with the cheapest terrain
+func (m *ProfileMap) GetMinGValue() uint32 {
+	minGValue := uint64(m.TerrainMap.GetMinGValue()) * uint64(m.profile.GetMinCostPercent()) / 100
+	return uint32(minGValue)
+}
@@ -0,0 +1,507 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	TraceVersion = 2
+	// the traces without the edges and the links of the map
+	traceOldVersion = 1
+)
+
+var (
+	ErrTraceBadVersion = errors.New("trace: unsupported version")
+	ErrTraceNoMap      = errors.New("trace: no map")
+)
+
+//========================
+//      SearchTrace
+//========================
+type TraceEventType string
+
+const (
+	TraceEventOpened    TraceEventType = "opened"
+	TraceEventExpanded  TraceEventType = "expanded"
+	TraceEventImproved  TraceEventType = "improved"
+	TraceEventJumpPoint TraceEventType = "jump"
+)
+
+type TraceEvent struct {
+	Type   TraceEventType `json:"type"`
+	Col    int            `json:"col"`
+	Row    int            `json:"row"`
+	GValue uint32         `json:"g"`
+	// the parent of the node, or the grid where the jump starts
+	ParentCol int  `json:"pcol,omitempty"`
+	ParentRow int  `json:"prow,omitempty"`
+	HasParent bool `json:"hasParent,omitempty"`
+	// the g value before the node is improved
+	OldGValue uint32 `json:"oldG,omitempty"`
+}
+
+// an edge different from the default one, moving to the dest grid with its g value
+type TraceEdge struct {
+	From      Grid   `json:"from"`
+	To        Grid   `json:"to"`
+	GValue    uint32 `json:"g"`
+	BCanCross bool   `json:"canCross"`
+}
+
+// the snapshot of a map, '#' can't be crossed and '.' can be crossed, the terrain
+// of a profile map is kept by the crossable states and the g values of the grids
+type TraceMap struct {
+	Cols    int          `json:"cols"`
+	Rows    int          `json:"rows"`
+	Grids   []string     `json:"grids"`
+	GValues []uint32     `json:"gValues"`
+	Edges   []*TraceEdge `json:"edges,omitempty"`
+	Links   []*Link      `json:"links,omitempty"`
+	// the min g value of the map, the base of the heuristic may be lower than
+	// the g values of the grids
+	MinGValue uint32 `json:"minG"`
+}
+
+func NewTraceMap(m NavigationMap) *TraceMap {
+	cols, rows := m.GetColRow()
+	t := &TraceMap{
+		Cols:      int(cols),
+		Rows:      int(rows),
+		Grids:     make([]string, 0, int(rows)),
+		GValues:   make([]uint32, 0, int(cols)*int(rows)),
+		Edges:     make([]*TraceEdge, 0),
+		Links:     make([]*Link, 0),
+		MinGValue: m.GetMinGValue(),
+	}
+
+	for row := 0; row < t.Rows; row++ {
+		var line strings.Builder
+		for col := 0; col < t.Cols; col++ {
+			if m.CanCross(col, row) {
+				line.WriteByte('.')
+			} else {
+				line.WriteByte('#')
+			}
+
+			t.GValues = append(t.GValues, m.GetGValue(col, row))
+		}
+
+		t.Grids = append(t.Grids, line.String())
+	}
+
+	for row := 0; row < t.Rows; row++ {
+		for col := 0; col < t.Cols; col++ {
+			t.addEdges(m, col, row)
+			t.Links = append(t.Links, getLinks(m, col, row)...)
+		}
+	}
+
+	return t
+}
+
+// keep the edges from the grid which are different from the default ones
+func (t *TraceMap) addEdges(m NavigationMap, col int, row int) {
+	if !hasEdgeCost(m, col, row) {
+		return
+	}
+
+	for _, vec := range ringVectors {
+		toCol := col + vec.X
+		toRow := row + vec.Y
+		if !isInMapBounds(m, toCol, toRow) {
+			continue
+		}
+
+		gValue, ok := getEdgeGValue(m, col, row, toCol, toRow)
+		if ok == m.CanCross(toCol, toRow) && (!ok || gValue == m.GetGValue(toCol, toRow)) {
+			continue
+		}
+
+		t.Edges = append(t.Edges, &TraceEdge{
+			From:      Grid{Col: col, Row: row},
+			To:        Grid{Col: toCol, Row: toRow},
+			GValue:    gValue,
+			BCanCross: ok,
+		})
+	}
+}
+
+// rebuild the map, so the search can run again
+func (t *TraceMap) ToGridMap() *GridMap {
+	m := NewGridMap(uint32(t.Cols), uint32(t.Rows), 0)
+	for row := 0; row < t.Rows && row < len(t.Grids); row++ {
+		for col := 0; col < t.Cols && col < len(t.Grids[row]); col++ {
+			m.SetCanCross(col, row, t.Grids[row][col] != '#')
+			idx := row*t.Cols + col
+			if idx < len(t.GValues) {
+				m.SetGValue(col, row, t.GValues[idx])
+			}
+		}
+	}
+
+	// the heuristic runs on the recorded min g value
+	m.minGValue = t.MinGValue
+	m.bMinDirty = false
+	return m
+}
+
+// rebuild the map with the edges and the links, so the search can run again
+func (t *TraceMap) ToNavigationMap() NavigationMap {
+	var m NavigationMap = t.ToGridMap()
+	if len(t.Edges) > 0 {
+		edgeLayer := NewEdgeLayer(m)
+		for _, edge := range t.Edges {
+			edgeLayer.SetEdge(&edge.From, &edge.To, edge.GValue, edge.BCanCross)
+		}
+
+		m = edgeLayer
+	}
+
+	if len(t.Links) > 0 {
+		linkLayer := NewLinkLayer(m)
+		for _, link := range t.Links {
+			linkLayer.AddLink(&link.From, &link.To, link.GValue, link.LinkType, false)
+		}
+
+		m = linkLayer
+	}
+
+	return m
+}
+
+// a search with everything to reproduce it
+type SearchTrace struct {
+	Version int               `json:"version"`
+	Finder  string            `json:"finder"`
+	Config  map[string]string `json:"config,omitempty"`
+	Map     *TraceMap         `json:"map"`
+	Start   Grid              `json:"start"`
+	Dst     Grid              `json:"dst"`
+	Events  []*TraceEvent     `json:"events"`
+	Path    []Grid            `json:"path,omitempty"`
+	Stats   SearchStats       `json:"stats"`
+}
+
+func (t *SearchTrace) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(t)
+}
+
+func (t *SearchTrace) SaveFile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = t.Save(file)
+	closeErr := file.Close()
+	if err != nil {
+		return err
+	}
+
+	return closeErr
+}
+
+func LoadTrace(r io.Reader) (*SearchTrace, error) {
+	t := &SearchTrace{}
+	err := json.NewDecoder(r).Decode(t)
+	if err != nil {
+		return nil, err
+	}
+
+	if t.Version != TraceVersion && t.Version != traceOldVersion {
+		return nil, ErrTraceBadVersion
+	}
+
+	if t.Map == nil {
+		return nil, ErrTraceNoMap
+	}
+
+	// the old traces have no min g value, take the one of the grids
+	if t.Version == traceOldVersion {
+		m := t.Map.ToGridMap()
+		m.updateMinGValue()
+		t.Map.MinGValue = m.GetMinGValue()
+	}
+
+	return t, nil
+}
+
+func LoadTraceFile(path string) (*SearchTrace, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	defer file.Close()
+	return LoadTrace(file)
+}
+
+//========================
+//     TraceRecorder
+//========================
+// a finder can be observed
+type ObservableFinder interface {
+	PathFinder
+	SetObserver(observer SearchObserver)
+}
+
+// record the events of a search as a SearchTrace
+type TraceRecorder struct {
+	trace *SearchTrace
+}
+
+func NewTraceRecorder() *TraceRecorder {
+	return &TraceRecorder{}
+}
+
+// snapshot the map and the query, call it before the search
+func (r *TraceRecorder) Begin(m NavigationMap, startGrid *Grid, dstGrid *Grid, finderName string, config map[string]string) {
+	r.trace = &SearchTrace{
+		Version: TraceVersion,
+		Finder:  finderName,
+		Config:  config,
+		Map:     NewTraceMap(m),
+		Start:   *startGrid,
+		Dst:     *dstGrid,
+		Events:  make([]*TraceEvent, 0),
+	}
+}
+
+// keep the path of the search, call it after the search
+func (r *TraceRecorder) End(fullPath []PathNode) *SearchTrace {
+	for _, node := range fullPath {
+		r.trace.Path = append(r.trace.Path, *node.GetGrid())
+	}
+
+	return r.trace
+}
+
+func (r *TraceRecorder) GetTrace() *SearchTrace {
+	return r.trace
+}
+
+func (r *TraceRecorder) OnNodeOpened(node PathNode) {
+	r.addNodeEvent(TraceEventOpened, node)
+}
+
+func (r *TraceRecorder) OnNodeExpanded(node PathNode) {
+	r.addNodeEvent(TraceEventExpanded, node)
+}
+
+func (r *TraceRecorder) OnNodeImproved(node PathNode, oldGValue uint32) {
+	event, ok := r.addNodeEvent(TraceEventImproved, node)
+	if ok {
+		event.OldGValue = oldGValue
+	}
+}
+
+func (r *TraceRecorder) OnJumpPointFound(parent PathNode, col int, row int, gValue uint32) {
+	if r.trace == nil {
+		return
+	}
+
+	grid := parent.GetGrid()
+	r.trace.Events = append(r.trace.Events, &TraceEvent{
+		Type:      TraceEventJumpPoint,
+		Col:       col,
+		Row:       row,
+		GValue:    gValue,
+		ParentCol: grid.Col,
+		ParentRow: grid.Row,
+		HasParent: true,
+	})
+}
+
+func (r *TraceRecorder) OnSearchFinished(stats *SearchStats) {
+	if r.trace == nil {
+		return
+	}
+
+	r.trace.Stats = *stats
+}
+
+// the g value and the parent of the node when the event happens
+func (r *TraceRecorder) addNodeEvent(eventType TraceEventType, node PathNode) (*TraceEvent, bool) {
+	if r.trace == nil {
+		return nil, false
+	}
+
+	grid := node.GetGrid()
+	event := &TraceEvent{
+		Type:   eventType,
+		Col:    grid.Col,
+		Row:    grid.Row,
+		GValue: node.GetMinGValue(),
+	}
+
+	parent := node.GetParent()
+	if parent != nil {
+		parentGrid := parent.GetGrid()
+		event.ParentCol = parentGrid.Col
+		event.ParentRow = parentGrid.Row
+		event.HasParent = true
+	}
+
+	r.trace.Events = append(r.trace.Events, event)
+	return event, true
+}
+
+// find the path and record the trace
+func RecordSearch(finder ObservableFinder, m NavigationMap, startGrid *Grid, dstGrid *Grid, finderName string, config map[string]string) (*SearchTrace, []PathNode, bool) {
+	recorder := NewTraceRecorder()
+	recorder.Begin(m, startGrid, dstGrid, finderName, config)
+	finder.SetObserver(recorder)
+	defer finder.SetObserver(nil)
+
+	fullPath, ok := finder.FindPath(m, startGrid, dstGrid)
+	return recorder.End(fullPath), fullPath, ok
+}
+
+//========================
+//     TraceReplayer
+//========================
+// step through the events of a trace, the state after each event can be rendered
+type TraceReplayer struct {
+	trace   *SearchTrace
+	step    int
+	open    map[Grid]bool
+	closed  map[Grid]bool
+	current *Grid
+}
+
+func NewTraceReplayer(trace *SearchTrace) *TraceReplayer {
+	r := &TraceReplayer{
+		trace: trace,
+	}
+
+	r.Rewind()
+	return r
+}
+
+func (r *TraceReplayer) Rewind() {
+	r.step = 0
+	r.open = make(map[Grid]bool)
+	r.closed = make(map[Grid]bool)
+	r.current = nil
+}
+
+// the count of the events applied
+func (r *TraceReplayer) GetStep() int {
+	return r.step
+}
+
+func (r *TraceReplayer) GetStepCount() int {
+	return len(r.trace.Events)
+}
+
+func (r *TraceReplayer) IsEnd() bool {
+	return r.step >= len(r.trace.Events)
+}
+
+// apply the next event, return it, or return false at the end
+func (r *TraceReplayer) Step() (*TraceEvent, bool) {
+	if r.IsEnd() {
+		return nil, false
+	}
+
+	event := r.trace.Events[r.step]
+	r.step++
+	grid := Grid{Col: event.Col, Row: event.Row}
+	switch event.Type {
+	case TraceEventOpened:
+		r.open[grid] = true
+		delete(r.closed, grid)
+	case TraceEventExpanded:
+		delete(r.open, grid)
+		r.closed[grid] = true
+		r.current = &grid
+	}
+
+	return event, true
+}
+
+// go to the state after the step count of events
+func (r *TraceReplayer) StepTo(step int) {
+	if step < r.step {
+		r.Rewind()
+	}
+
+	for r.step < step {
+		if _, ok := r.Step(); !ok {
+			return
+		}
+	}
+}
+
+func (r *TraceReplayer) IsOpen(col int, row int) bool {
+	return r.open[Grid{Col: col, Row: row}]
+}
+
+func (r *TraceReplayer) IsClosed(col int, row int) bool {
+	return r.closed[Grid{Col: col, Row: row}]
+}
+
+// the last expanded grid
+func (r *TraceReplayer) GetCurrent() (*Grid, bool) {
+	if r.current == nil {
+		return nil, false
+	}
+
+	return r.current, true
+}
+
+// draw the state, '@' current, 'S' start, 'D' dest, '*' path (at the end),
+// 'o' open, 'x' closed, '#' can't cross, '.' others
+func (r *TraceReplayer) Render() string {
+	traceMap := r.trace.Map
+	path := make(map[Grid]bool)
+	if r.IsEnd() {
+		for _, grid := range r.trace.Path {
+			path[grid] = true
+		}
+	}
+
+	var sb strings.Builder
+	for row := 0; row < traceMap.Rows; row++ {
+		for col := 0; col < traceMap.Cols; col++ {
+			sb.WriteByte(r.getGridChar(col, row, path))
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+func (r *TraceReplayer) getGridChar(col int, row int, path map[Grid]bool) byte {
+	grid := Grid{Col: col, Row: row}
+	switch {
+	case r.current != nil && r.current.IsSameGrid(&grid):
+		return '@'
+	case r.trace.Start.IsSameGrid(&grid):
+		return 'S'
+	case r.trace.Dst.IsSameGrid(&grid):
+		return 'D'
+	case path[grid]:
+		return '*'
+	case r.open[grid]:
+		return 'o'
+	case r.closed[grid]:
+		return 'x'
+	}
+
+	traceMap := r.trace.Map
+	if row < len(traceMap.Grids) && col < len(traceMap.Grids[row]) {
+		return traceMap.Grids[row][col]
+	}
+
+	return '.'
+}
@@ -0,0 +1,119 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestTraceMapEdgesAndLinks(t *testing.T) {
+	edgeLayer := newTestWallLayer(t)
+	edgeLayer.SetEdge(NewGrid(0, 1), NewGrid(0, 0), 7, true)
+	m := NewLinkLayer(edgeLayer)
+	m.AddLink(NewGrid(0, 0), NewGrid(4, 0), 10, LinkTypeTeleport, false)
+
+	startGrid := NewGrid(0, 1)
+	dstGrid := NewGrid(4, 1)
+	trace, _, ok := RecordSearch(NewAStar(), m, startGrid, dstGrid, "astar", nil)
+	if !ok {
+		t.Fatalf("no path")
+	}
+
+	var buf bytes.Buffer
+	if err := trace.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// (0, 1) -> (1, 1) -> (1, 0) -> (0, 0) -> link -> (4, 0) -> (4, 1), not through the wall
+	want, _ := getPathGValue(t, NewAStar(), m, startGrid, dstGrid)
+	got, ok := getPathGValue(t, NewAStar(), loaded.Map.ToNavigationMap(), startGrid, dstGrid)
+	if !ok || got != want || want != 14 {
+		t.Errorf("got g value %d (%v) on the loaded map, want %d on the map, want 14", got, ok, want)
+	}
+}
+
+func TestTraceImprovedEvent(t *testing.T) {
+	m := newRandomTestMap(1, 32, 32, 0.2, 9)
+	count := 0
+	for i := 0; i < 32; i++ {
+		trace, _, _ := RecordSearch(NewAStar(), m, NewGrid(i, 0), NewGrid(31-i, 31), "astar", nil)
+		for _, event := range trace.Events {
+			if event.Type != TraceEventImproved {
+				continue
+			}
+
+			count++
+			if event.GValue >= event.OldGValue {
+				t.Errorf("improved g value %d is not lower than the old one %d", event.GValue, event.OldGValue)
+			}
+		}
+	}
+
+	if count == 0 {
+		t.Errorf("no node is improved")
+	}
+}
+
+// the search on the loaded map runs the same events, and the replays show the same states
+func TestTraceReplay(t *testing.T) {
+	terrains := NewTerrainLayer(newRandomTestMap(44, 24, 24, 0.2, 1))
+	// the swamp of the profile is not in the map, the min g value is lower than the grids
+	profile := NewMovementProfile("walk")
+	profile.SetTerrain(0, true, 1000)
+	profile.SetTerrain(1, true, 100)
+	m := NewProfileMap(terrains, profile)
+
+	trace, _, ok := RecordSearch(NewAStar(), m, NewGrid(0, 0), NewGrid(23, 23), "astar", nil)
+	if !ok {
+		t.Fatal("no path")
+	}
+
+	var buf bytes.Buffer
+	if err := trace.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadTrace(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loadedMap := loaded.Map.ToNavigationMap()
+	if loadedMap.GetMinGValue() != m.GetMinGValue() {
+		t.Errorf("got min g value %d on the loaded map, want %d", loadedMap.GetMinGValue(), m.GetMinGValue())
+	}
+
+	replayed, _, _ := RecordSearch(NewAStar(), loadedMap, &loaded.Start, &loaded.Dst, loaded.Finder, loaded.Config)
+	if len(replayed.Events) != len(trace.Events) {
+		t.Fatalf("got %d events on the loaded map, want %d", len(replayed.Events), len(trace.Events))
+	}
+
+	want := NewTraceReplayer(trace)
+	got := NewTraceReplayer(replayed)
+	for i, event := range trace.Events {
+		if *replayed.Events[i] != *event || *loaded.Events[i] != *event {
+			t.Fatalf("step %d: got event %v, loaded %v, want %v", i, replayed.Events[i], loaded.Events[i], event)
+		}
+
+		want.Step()
+		got.Step()
+		if got.Render() != want.Render() {
+			t.Fatalf("step %d: got state\n%s\nwant\n%s", i, got.Render(), want.Render())
+		}
+	}
+}
+
+func TestTraceNoMap(t *testing.T) {
+	if _, err := LoadTrace(strings.NewReader(`{"version": 2}`)); err != ErrTraceNoMap {
+		t.Errorf("got error %v, want %v", err, ErrTraceNoMap)
+	}
+}
@@ -48,6 +48,45 @@ func (g *Grid) IsSameGrid2(col int, row int) bool {
 	return true
 }
 
+//========================
+//          Grid3
+//========================
+type Grid3 struct {
+	Col   int
+	Row   int
+	Layer int
+}
+
+func NewGrid3(col int, row int, layer int) *Grid3 {
+	return &Grid3{
+		Col:   col,
+		Row:   row,
+		Layer: layer,
+	}
+}
+
+func (g *Grid3) Update(col int, row int, layer int) {
+	g.Col = col
+	g.Row = row
+	g.Layer = layer
+}
+
+func (g *Grid3) IsSameGrid(g2 *Grid3) bool {
+	if g.Col != g2.Col {
+		return false
+	}
+
+	if g.Row != g2.Row {
+		return false
+	}
+
+	if g.Layer != g2.Layer {
+		return false
+	}
+
+	return true
+}
+
 //========================
 //         Vector
 //========================
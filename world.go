@@ -0,0 +1,235 @@
+// Copyright 2022 Guan Jianchang. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package nav
+
+import "math"
+
+// the max distance in grids to look for a crossable grid by FindPathWorld
+const worldSnapRadius = 16
+
+//========================
+//        WorldPos
+//========================
+type WorldPos struct {
+	X float64
+	Y float64
+}
+
+func NewWorldPos(x float64, y float64) *WorldPos {
+	return &WorldPos{
+		X: x,
+		Y: y,
+	}
+}
+
+func (p *WorldPos) GetDistance(p2 *WorldPos) float64 {
+	return math.Hypot(p2.X-p.X, p2.Y-p.Y)
+}
+
+//========================
+//     GridTransform
+//========================
+// map the world positions to the grids, the grid (0, 0) has its left top
+// corner at the origin, the x axis of the grids turns the rotation (in radians)
+// from the x axis of the world
+type GridTransform struct {
+	originX  float64
+	originY  float64
+	cellSize float64
+	rotation float64
+	cos      float64
+	sin      float64
+}
+
+func NewGridTransform(originX float64, originY float64, cellSize float64) *GridTransform {
+	t := &GridTransform{
+		originX:  originX,
+		originY:  originY,
+		cellSize: cellSize,
+	}
+
+	t.SetRotation(0)
+	return t
+}
+
+func (t *GridTransform) SetRotation(rotation float64) {
+	t.rotation = rotation
+	t.cos = math.Cos(rotation)
+	t.sin = math.Sin(rotation)
+}
+
+func (t *GridTransform) GetRotation() float64 {
+	return t.rotation
+}
+
+func (t *GridTransform) GetCellSize() float64 {
+	return t.cellSize
+}
+
+// the grid contains the position
+func (t *GridTransform) WorldToGrid(pos *WorldPos) *Grid {
+	x, y := t.worldToLocal(pos)
+	return NewGrid(int(math.Floor(x)), int(math.Floor(y)))
+}
+
+// the center of the grid
+func (t *GridTransform) GridToWorld(grid *Grid) *WorldPos {
+	return t.localToWorld(float64(grid.Col)+0.5, float64(grid.Row)+0.5)
+}
+
+// the position in the grid units, without the origin and the rotation
+func (t *GridTransform) worldToLocal(pos *WorldPos) (float64, float64) {
+	dx := pos.X - t.originX
+	dy := pos.Y - t.originY
+	x := dx*t.cos + dy*t.sin
+	y := -dx*t.sin + dy*t.cos
+	return x / t.cellSize, y / t.cellSize
+}
+
+func (t *GridTransform) localToWorld(x float64, y float64) *WorldPos {
+	x *= t.cellSize
+	y *= t.cellSize
+	return NewWorldPos(t.originX+x*t.cos-y*t.sin, t.originY+x*t.sin+y*t.cos)
+}
+
+// whether the straight line between the positions only crosses the grids can be crossed,
+// the line through the corner of two grids needs both side grids
+func hasLineOfSight(m NavigationMap, t *GridTransform, from *WorldPos, to *WorldPos) bool {
+	x0, y0 := t.worldToLocal(from)
+	x1, y1 := t.worldToLocal(to)
+	col := int(math.Floor(x0))
+	row := int(math.Floor(y0))
+	stepX, maxX, deltaX := getLineTraversal(x0, x1)
+	stepY, maxY, deltaY := getLineTraversal(y0, y1)
+	for {
+		if !m.CanCross(col, row) {
+			return false
+		}
+
+		// the end of the line is in the grid
+		if maxX > 1 && maxY > 1 {
+			return true
+		}
+
+		switch {
+		case maxX < maxY:
+			col += stepX
+			maxX += deltaX
+		case maxY < maxX:
+			row += stepY
+			maxY += deltaY
+		default:
+			if !m.CanCross(col+stepX, row) || !m.CanCross(col, row+stepY) {
+				return false
+			}
+
+			col += stepX
+			row += stepY
+			maxX += deltaX
+			maxY += deltaY
+		}
+	}
+}
+
+// the step of the grid, the ratio of the line to the first grid border, and the ratio
+// between two grid borders along an axis
+func getLineTraversal(v0 float64, v1 float64) (int, float64, float64) {
+	d := v1 - v0
+	if d == 0 {
+		return 0, math.Inf(1), math.Inf(1)
+	}
+
+	if d > 0 {
+		return 1, (math.Floor(v0) + 1 - v0) / d, 1 / d
+	}
+
+	return -1, (v0 - math.Floor(v0)) / -d, -1 / d
+}
+
+//========================
+//     FindPathWorld
+//========================
+// the crossable grid nearest to the position, look for it within the max distance in grids
+func SnapToCrossable(m NavigationMap, t *GridTransform, pos *WorldPos, maxRadius int) (*Grid, bool) {
+	x, y := t.worldToLocal(pos)
+	center := NewGrid(int(math.Floor(x)), int(math.Floor(y)))
+	if m.CanCross(center.Col, center.Row) {
+		return center, true
+	}
+
+	var nearest *Grid
+	minDistSq := math.MaxFloat64
+	for radius := 1; radius <= maxRadius; radius++ {
+		// the grids of the ring are at least radius - 0.5 away from the position
+		ringDist := float64(radius) - 0.5
+		if ringDist*ringDist > minDistSq {
+			break
+		}
+
+		for row := center.Row - radius; row <= center.Row+radius; row++ {
+			for col := center.Col - radius; col <= center.Col+radius; col++ {
+				// the grids on the ring only
+				if absInt(row-center.Row) != radius && absInt(col-center.Col) != radius {
+					continue
+				}
+
+				if !isInMapBounds(m, col, row) || !m.CanCross(col, row) {
+					continue
+				}
+
+				dx := float64(col) + 0.5 - x
+				dy := float64(row) + 0.5 - y
+				distSq := dx*dx + dy*dy
+				if distSq < minDistSq {
+					minDistSq = distSq
+					nearest = NewGrid(col, row)
+				}
+			}
+		}
+	}
+
+	return nearest, nearest != nil
+}
+
+// find the path between the world positions, the positions in the grids can't be crossed
+// are snapped to the nearest crossable grids. the waypoints are the centers of the path
+// grids, but the first and the last are the exact positions
+func FindPathWorld(finder PathFinder, m NavigationMap, t *GridTransform, startPos *WorldPos, dstPos *WorldPos) ([]*WorldPos, bool) {
+	startGrid, ok := SnapToCrossable(m, t, startPos, worldSnapRadius)
+	if !ok {
+		return nil, false
+	}
+
+	dstGrid, ok := SnapToCrossable(m, t, dstPos, worldSnapRadius)
+	if !ok {
+		return nil, false
+	}
+
+	fullPath, ok := finder.FindPath(m, startGrid, dstGrid)
+	if !ok {
+		return nil, false
+	}
+
+	waypoints := make([]*WorldPos, 0, len(fullPath)+2)
+	waypoints = append(waypoints, NewWorldPos(startPos.X, startPos.Y))
+
+	// walk to the center of the snapped grid first, the straight line may cross the walls
+	bStartSnapped := !t.WorldToGrid(startPos).IsSameGrid(startGrid)
+	if bStartSnapped {
+		waypoints = append(waypoints, t.GridToWorld(startGrid))
+	}
+
+	for i := 1; i < len(fullPath)-1; i++ {
+		waypoints = append(waypoints, t.GridToWorld(fullPath[i].GetGrid()))
+	}
+
+	// both positions may be snapped to the same grid
+	if !t.WorldToGrid(dstPos).IsSameGrid(dstGrid) && !(bStartSnapped && len(fullPath) <= 1) {
+		waypoints = append(waypoints, t.GridToWorld(dstGrid))
+	}
+
+	waypoints = append(waypoints, NewWorldPos(dstPos.X, dstPos.Y))
+	return waypoints, true
+}